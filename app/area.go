@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/PbrtCraft/pbrtcraftdrv/mc"
+	"github.com/PbrtCraft/pbrtcraftdrv/mcwdrv"
+)
+
+// areaQuery is the area around a render target in query string:
+// world, radius and either player or x, y, z and dim
+type areaQuery struct {
+	World     *mc.World
+	Dimension string
+	Center    [3]int
+	Radius    int
+}
+
+func findPlayer(world *mc.World, name string) *mc.Player {
+	for _, player := range world.Players {
+		if player.Name == name {
+			return player
+		}
+	}
+	return nil
+}
+
+// renderTarget is mcwdrv.Target in request
+type renderTarget struct {
+	X         float64 `json:"x"`
+	Y         float64 `json:"y"`
+	Z         float64 `json:"z"`
+	Yaw       float64 `json:"yaw"`
+	Pitch     float64 `json:"pitch"`
+	Dimension string  `json:"dim"`
+}
+
+// validateTarget check render target against the world
+func validateTarget(worldPath string, t *mcwdrv.Target) error {
+	world := findWorld(worldPath)
+	if world == nil {
+		return fmt.Errorf("app.validateTarget: world %s not found", worldPath)
+	}
+	err := world.ValidatePosition(t.Dimension, [3]float64{t.X, t.Y, t.Z}, t.Pitch)
+	if err != nil {
+		return fmt.Errorf("app.validateTarget: %s", err)
+	}
+	return nil
+}
+
+func parseAreaQuery(r *http.Request) (*areaQuery, error) {
+	query := r.URL.Query()
+	q := &areaQuery{}
+	q.World = findWorld(query.Get("world"))
+	if q.World == nil {
+		return nil, fmt.Errorf("app.parseAreaQuery: world %s not found", query.Get("world"))
+	}
+
+	var err error
+	q.Radius, err = strconv.Atoi(query.Get("radius"))
+	if err != nil || q.Radius < 0 {
+		return nil, fmt.Errorf("app.parseAreaQuery: bad radius %s", query.Get("radius"))
+	}
+
+	if name := query.Get("player"); name != "" {
+		player := findPlayer(q.World, name)
+		if player == nil {
+			return nil, fmt.Errorf("app.parseAreaQuery: player %s not found", name)
+		}
+		q.Dimension = player.Dimension
+		for i := 0; i < 3; i++ {
+			q.Center[i] = int(math.Floor(player.Pos[i]))
+		}
+		return q, nil
+	}
+
+	q.Dimension = query.Get("dim")
+	for i, key := range []string{"x", "y", "z"} {
+		q.Center[i], err = strconv.Atoi(query.Get(key))
+		if err != nil {
+			return nil, fmt.Errorf("app.parseAreaQuery: bad %s %s", key, query.Get(key))
+		}
+	}
+	return q, nil
+}
+
+func coverageHandler(w http.ResponseWriter, r *http.Request) {
+	q, err := parseAreaQuery(r)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	coverage, err := q.World.ChunkCoverage(r.Context(), q.Dimension, q.Center, q.Radius)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(coverage)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprint(w, string(bytes))
+}
+
+// inventoryHandler return blocks in the area, to predict render cost
+func inventoryHandler(w http.ResponseWriter, r *http.Request) {
+	q, err := parseAreaQuery(r)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	inventory, err := q.World.BlockInventory(r.Context(), q.Dimension, q.Center, q.Radius)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(inventory)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprint(w, string(bytes))
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/PbrtCraft/pbrtcraftdrv/mc"
+	"github.com/PbrtCraft/pbrtcraftdrv/mcwdrv"
+)
+
+// assetsDir is where textures of client versions are extracted, by version ID,
+// assetsMutex is held while they are extracted or a render is being started with them
+var (
+	assetsDir   string
+	assetsMutex sync.Mutex
+)
+
+// clientVersion is an installed version, and whether its assets are extracted
+type clientVersion struct {
+	*mc.ClientVersion
+	Extracted bool `json:"extracted"`
+}
+
+func initAssets(workdir string) {
+	assetsDir = path.Join(workdir, "assets")
+}
+
+// clientDirs return game folders of client sources and the auto detected one
+func clientDirs() []string {
+	dirs := []string{}
+	seen := map[string]bool{}
+	for _, s := range getWorldSources() {
+		if s.Type != sourceClient {
+			continue
+		}
+		for _, inst := range s.Installations {
+			if !seen[inst.Dir] {
+				seen[inst.Dir] = true
+				dirs = append(dirs, inst.Dir)
+			}
+		}
+	}
+	if dir, err := mc.FindMinecraft(); err == nil && !seen[dir] {
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// clientVersions return installed versions of all clients, the first one is kept
+// if clients have the same version
+func clientVersions() ([]*clientVersion, error) {
+	ret := []*clientVersion{}
+	seen := map[string]bool{}
+	for _, dir := range clientDirs() {
+		versions, err := mc.ListClientVersions(dir)
+		if err != nil {
+			return nil, fmt.Errorf("app.clientVersions: %s", err)
+		}
+		for _, v := range versions {
+			if seen[v.ID] {
+				continue
+			}
+			seen[v.ID] = true
+			_, err := os.Stat(path.Join(assetsDir, v.ID))
+			ret = append(ret, &clientVersion{ClientVersion: v, Extracted: err == nil})
+		}
+	}
+	return ret, nil
+}
+
+// versionAssets return assets folder of version, which is extracted
+// from client jar if not yet, or force is set. assetsMutex must be held
+func versionAssets(id string, force bool) (string, error) {
+	versions, err := clientVersions()
+	if err != nil {
+		return "", fmt.Errorf("app.versionAssets: %s", err)
+	}
+	var version *clientVersion
+	for _, v := range versions {
+		if v.ID == id {
+			version = v
+		}
+	}
+	if version == nil {
+		return "", fmt.Errorf("app.versionAssets: client version %s not installed", id)
+	}
+
+	dir := path.Join(assetsDir, version.ID)
+	if _, err := os.Stat(dir); err == nil && !force {
+		return dir, nil
+	}
+	log.Println("Extract assets of", version.ID, "from", version.Jar)
+	if err := mc.ExtractAssets(version.Jar, dir); err != nil {
+		return "", fmt.Errorf("app.versionAssets: %s", err)
+	}
+	return dir, nil
+}
+
+func clientVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	versions, err := clientVersions()
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(versions)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprint(w, string(bytes))
+}
+
+// extractAssetsHandler extract assets of version again,
+// refused while a render is running since it may read them
+func extractAssetsHandler(w http.ResponseWriter, r *http.Request) {
+	assetsMutex.Lock()
+	defer assetsMutex.Unlock()
+	if mcwDriver.GetStatus() != mcwdrv.StatusIdle {
+		log.Println("app.extractAssetsHandler: render is running")
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprintf(w, "Assets can not be extracted again while a render is running")
+		return
+	}
+	if _, err := versionAssets(r.URL.Query().Get("version"), true); err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+	clientVersionsHandler(w, r)
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/PbrtCraft/pbrtcraftdrv/mc"
+	"github.com/PbrtCraft/pbrtcraftdrv/mcwdrv"
+)
+
+// bookmarksDir keeps bookmarks of each world in a json file
+var (
+	bookmarksDir   string
+	bookmarksMutex sync.Mutex
+)
+
+// maxBookmarksImport is the largest bookmarks file can be imported
+const maxBookmarksImport = 1 << 20
+
+// bookmark is a named view of a world to render again
+type bookmark struct {
+	Name   string       `json:"name"`
+	Target renderTarget `json:"target"`
+	Camera mcwdrv.Class `json:"camera"`
+}
+
+// worldBookmarks is a bookmarks file, exported bookmarks have the same format
+type worldBookmarks struct {
+	World     string      `json:"world"`
+	Bookmarks []*bookmark `json:"bookmarks"`
+}
+
+func initBookmarks(dataDir string) error {
+	bookmarksDir = path.Join(dataDir, "bookmarks")
+	if err := os.MkdirAll(bookmarksDir, os.ModePerm); err != nil {
+		return fmt.Errorf("app.initBookmarks: %s", err)
+	}
+	return nil
+}
+
+// bookmarksFile return file keeping bookmarks of world, named by hash of its path
+func bookmarksFile(worldPath string) string {
+	sum := sha1.Sum([]byte(worldPath))
+	return path.Join(bookmarksDir, hex.EncodeToString(sum[:])+".json")
+}
+
+// loadBookmarks return bookmarks of world, empty if none is saved
+func loadBookmarks(worldPath string) ([]*bookmark, error) {
+	bs, err := ioutil.ReadFile(bookmarksFile(worldPath))
+	if os.IsNotExist(err) {
+		return []*bookmark{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("app.loadBookmarks: %s", err)
+	}
+	var wb worldBookmarks
+	if err := json.Unmarshal(bs, &wb); err != nil {
+		return nil, fmt.Errorf("app.loadBookmarks: %s", err)
+	}
+	if wb.Bookmarks == nil {
+		wb.Bookmarks = []*bookmark{}
+	}
+	return wb.Bookmarks, nil
+}
+
+// storeBookmarks replace bookmarks file of world
+func storeBookmarks(worldPath string, bookmarks []*bookmark) error {
+	bs, err := json.MarshalIndent(&worldBookmarks{World: worldPath, Bookmarks: bookmarks}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("app.storeBookmarks: %s", err)
+	}
+	fn := bookmarksFile(worldPath)
+	if err := ioutil.WriteFile(fn+".tmp", bs, 0666); err != nil {
+		return fmt.Errorf("app.storeBookmarks: %s", err)
+	}
+	if err := os.Rename(fn+".tmp", fn); err != nil {
+		return fmt.Errorf("app.storeBookmarks: %s", err)
+	}
+	return nil
+}
+
+// validateBookmark check bookmark has a name and its target is in the world
+func validateBookmark(worldPath string, b *bookmark) error {
+	b.Name = strings.TrimSpace(b.Name)
+	if b.Name == "" {
+		return fmt.Errorf("app.validateBookmark: bookmark has no name")
+	}
+	if b.Target.Dimension == "" {
+		b.Target.Dimension = mc.DimensionOverworld
+	}
+	if b.Camera.Name == "" {
+		return fmt.Errorf("app.validateBookmark: %s: no camera", b.Name)
+	}
+	if err := validateTarget(worldPath, (*mcwdrv.Target)(&b.Target)); err != nil {
+		return fmt.Errorf("app.validateBookmark: %s: %s", b.Name, err)
+	}
+	return nil
+}
+
+func findBookmark(bookmarks []*bookmark, name string) int {
+	for i, b := range bookmarks {
+		if b.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// updateBookmarks apply f to bookmarks of world and store them,
+// return bookmarks after the update
+func updateBookmarks(worldPath string, f func([]*bookmark) ([]*bookmark, error)) ([]*bookmark, error) {
+	bookmarksMutex.Lock()
+	defer bookmarksMutex.Unlock()
+	bookmarks, err := loadBookmarks(worldPath)
+	if err != nil {
+		return nil, fmt.Errorf("app.updateBookmarks: %s", err)
+	}
+	bookmarks, err = f(bookmarks)
+	if err != nil {
+		return nil, fmt.Errorf("app.updateBookmarks: %s", err)
+	}
+	if err := storeBookmarks(worldPath, bookmarks); err != nil {
+		return nil, fmt.Errorf("app.updateBookmarks: %s", err)
+	}
+	return bookmarks, nil
+}
+
+// bookmarksWorld return world in query, bookmarks are kept for found worlds only
+func bookmarksWorld(r *http.Request) (string, error) {
+	worldPath := r.URL.Query().Get("world")
+	if findWorld(worldPath) == nil {
+		return "", fmt.Errorf("app.bookmarksWorld: world %s not found", worldPath)
+	}
+	return worldPath, nil
+}
+
+func writeBookmarks(w http.ResponseWriter, bookmarks []*bookmark) {
+	bytes, err := json.Marshal(bookmarks)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprint(w, string(bytes))
+}
+
+func listBookmarksHandler(w http.ResponseWriter, r *http.Request) {
+	worldPath, err := bookmarksWorld(r)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	bookmarksMutex.Lock()
+	bookmarks, err := loadBookmarks(worldPath)
+	bookmarksMutex.Unlock()
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writeBookmarks(w, bookmarks)
+}
+
+// saveBookmarkHandler create a bookmark, or replace the one named by name in query
+func saveBookmarkHandler(w http.ResponseWriter, r *http.Request) {
+	worldPath, err := bookmarksWorld(r)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var b bookmark
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err := validateBookmark(worldPath, &b); err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	old := r.URL.Query().Get("name")
+	bookmarks, err := updateBookmarks(worldPath, func(bookmarks []*bookmark) ([]*bookmark, error) {
+		index := len(bookmarks)
+		if old != "" {
+			index = findBookmark(bookmarks, old)
+			if index < 0 {
+				return nil, fmt.Errorf("bookmark %s not found", old)
+			}
+		}
+		if i := findBookmark(bookmarks, b.Name); i >= 0 && i != index {
+			return nil, fmt.Errorf("bookmark %s exists", b.Name)
+		}
+		if index == len(bookmarks) {
+			return append(bookmarks, &b), nil
+		}
+		bookmarks[index] = &b
+		return bookmarks, nil
+	})
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+	writeBookmarks(w, bookmarks)
+}
+
+func deleteBookmarkHandler(w http.ResponseWriter, r *http.Request) {
+	worldPath, err := bookmarksWorld(r)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+	bookmarks, err := updateBookmarks(worldPath, func(bookmarks []*bookmark) ([]*bookmark, error) {
+		index := findBookmark(bookmarks, name)
+		if index < 0 {
+			return nil, fmt.Errorf("bookmark %s not found", name)
+		}
+		return append(bookmarks[:index], bookmarks[index+1:]...), nil
+	})
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+	writeBookmarks(w, bookmarks)
+}
+
+// exportBookmarksHandler download bookmarks of world as a json file
+func exportBookmarksHandler(w http.ResponseWriter, r *http.Request) {
+	worldPath, err := bookmarksWorld(r)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	bookmarksMutex.Lock()
+	bookmarks, err := loadBookmarks(worldPath)
+	bookmarksMutex.Unlock()
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	bytes, err := json.MarshalIndent(&worldBookmarks{World: worldPath, Bookmarks: bookmarks}, "", "  ")
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf("attachment; filename=%q", path.Base(worldPath)+"-bookmarks.json"))
+	w.Write(bytes)
+}
+
+// importBookmarksHandler add bookmarks of an exported file to world,
+// bookmarks with the same name are replaced. Nothing is added if any
+// bookmark in the file is invalid or two of them have the same name
+func importBookmarksHandler(w http.ResponseWriter, r *http.Request) {
+	worldPath, err := bookmarksWorld(r)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBookmarksImport)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	var wb worldBookmarks
+	if err := json.NewDecoder(file).Decode(&wb); err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "Not a bookmarks file: %s", err)
+		return
+	}
+	// Problems of all bookmarks are reported, entries are numbered from 1
+	problems := []string{}
+	names := map[string]int{}
+	for i, b := range wb.Bookmarks {
+		if b == nil {
+			problems = append(problems, fmt.Sprintf("bookmark %d is null", i+1))
+			continue
+		}
+		if err := validateBookmark(worldPath, b); err != nil {
+			problems = append(problems, fmt.Sprintf("bookmark %d: %s", i+1, err))
+			continue
+		}
+		if j, ok := names[b.Name]; ok {
+			problems = append(problems, fmt.Sprintf("bookmark %d: name %s is used by bookmark %d", i+1, b.Name, j+1))
+			continue
+		}
+		names[b.Name] = i
+	}
+	if len(problems) > 0 {
+		err := fmt.Errorf("app.importBookmarksHandler: %s", strings.Join(problems, "; "))
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	bookmarks, err := updateBookmarks(worldPath, func(bookmarks []*bookmark) ([]*bookmark, error) {
+		for _, b := range wb.Bookmarks {
+			if i := findBookmark(bookmarks, b.Name); i >= 0 {
+				bookmarks[i] = b
+			} else {
+				bookmarks = append(bookmarks, b)
+			}
+		}
+		return bookmarks, nil
+	})
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	log.Println("Import", len(wb.Bookmarks), "bookmark(s) to", worldPath)
+	writeBookmarks(w, bookmarks)
+}
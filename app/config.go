@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"io/ioutil"
+	"path"
 
 	"github.com/PbrtCraft/pbrtcraftdrv/mcwdrv"
 	yaml "gopkg.in/yaml.v2"
@@ -13,8 +14,18 @@ type appConfig struct {
 
 	PythonFile typeFile `yaml:"python_file"`
 
+	// Phenomenons filled with time and weather of world
+	MatchWorld []*matchWorldRule `yaml:"match_world"`
+
+	// Directory for data of app, like caches, default is workdir/data
+	DataDir string `yaml:"data_dir"`
+
 	Minecraft struct {
-		Directory string `yaml:"directory"`
+		Directory     string         `yaml:"directory"`
+		Sources       []*worldSource `yaml:"sources"`
+		SearchRoots   []string       `yaml:"search_roots"`   // Extra folders to find clients, instances and servers in
+		WatchInterval int            `yaml:"watch_interval"` // Seconds, default is 5, -1 to disable
+		UUID          uuidConfig     `yaml:"uuid"`
 	} `yaml:"minecraft"`
 
 	Srv struct {
@@ -33,5 +44,9 @@ func getAppConfig(filename string) (*appConfig, error) {
 	if err != nil {
 		return nil, fmt.Errorf("app.getAppConfig: %s", err)
 	}
+
+	if c.DataDir == "" && c.MWCDriver != nil {
+		c.DataDir = path.Join(c.MWCDriver.Workdir, "data")
+	}
 	return &c, nil
 }
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type event struct {
+	Name string
+	Data interface{}
+}
+
+// eventHub broadcasts server side events to connected dashboards
+type eventHub struct {
+	mutex   sync.Mutex
+	clients map[chan event]struct{}
+	done    chan struct{}
+}
+
+var events = &eventHub{
+	clients: map[chan event]struct{}{},
+	done:    make(chan struct{}),
+}
+
+func (h *eventHub) subscribe() chan event {
+	ch := make(chan event, 8)
+	h.mutex.Lock()
+	h.clients[ch] = struct{}{}
+	h.mutex.Unlock()
+	return ch
+}
+
+func (h *eventHub) unsubscribe(ch chan event) {
+	h.mutex.Lock()
+	delete(h.clients, ch)
+	h.mutex.Unlock()
+}
+
+func (h *eventHub) publish(name string, data interface{}) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for ch := range h.clients {
+		select {
+		case ch <- event{Name: name, Data: data}:
+		default:
+			// Slow client, drop the event rather than blocking others
+		}
+	}
+}
+
+// close ends all streams, otherwise srv.Shutdown waits for them forever
+func (h *eventHub) close() {
+	close(h.done)
+}
+
+func eventsHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		log.Println("app.eventsHandler: streaming unsupported")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher.Flush()
+
+	ch := events.subscribe()
+	defer events.unsubscribe(ch)
+
+	keepAlive := time.NewTicker(30 * time.Second)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-events.done:
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case e := <-ch:
+			bs, err := json.Marshal(e.Data)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Name, bs)
+		}
+		flusher.Flush()
+	}
+}
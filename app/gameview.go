@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/PbrtCraft/pbrtcraftdrv/mc"
+	"github.com/PbrtCraft/pbrtcraftdrv/mcwdrv"
+)
+
+// gameViewResult is camera and radius filled from options.txt of the client
+type gameViewResult struct {
+	Options      *mc.GameOptions `json:"options"`      // nil if no options.txt is found
+	Installation string          `json:"installation"` // Label of installation having options.txt
+	Camera       mcwdrv.Class    `json:"camera"`
+	Radius       int             `json:"radius"`
+	Filled       []*matchedParam `json:"filled"`
+	Warnings     []string        `json:"warnings"`
+}
+
+// realPath return absolute path of p with symlinks resolved, or p if it can not be resolved
+func realPath(p string) string {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return p
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved
+	}
+	return abs
+}
+
+// findWorldInstallation return installation the world is saved in and its
+// options.txt if it has one, or the first one having options.txt for worlds
+// of servers and backups. options.txt of each installation is read once.
+func findWorldInstallation(world *mc.World) (*mc.Installation, *mc.GameOptions, bool) {
+	saves := realPath(filepath.Dir(world.Path))
+	var fallback *mc.Installation
+	var fallbackOpts *mc.GameOptions
+	for _, s := range getWorldSources() {
+		for _, inst := range s.Installations {
+			own := realPath(inst.Saves) == saves
+			if !own && fallback != nil {
+				continue
+			}
+			opts, err := mc.ReadGameOptions(inst.Dir)
+			if err != nil {
+				continue
+			}
+			if own {
+				return inst, opts, true
+			}
+			fallback, fallbackOpts = inst, opts
+		}
+	}
+	return fallback, fallbackOpts, false
+}
+
+// isFOVParam check whether a camera param is a field of view
+func isFOVParam(name string) bool {
+	name = strings.ToLower(name)
+	return strings.Contains(name, "fov") || strings.Contains(name, "field_of_view")
+}
+
+// pbrtFOV convert vertical fov of the game to fov of pbrt,
+// which is along the shorter side of image
+func pbrtFOV(fov float64, width, height int) float64 {
+	if width <= 0 || height <= 0 || width >= height {
+		return fov
+	}
+	half := math.Tan(fov / 2 * math.Pi / 180)
+	return 2 * math.Atan(half*float64(width)/float64(height)) * 180 / math.Pi
+}
+
+// matchGameView fill fov params of camera and radius from options.txt
+// of the installation of world
+func matchGameView(world *mc.World, camera mcwdrv.Class, width, height int) *gameViewResult {
+	result := &gameViewResult{
+		Camera:   camera,
+		Filled:   []*matchedParam{},
+		Warnings: []string{},
+	}
+	inst, opts, own := findWorldInstallation(world)
+	if inst == nil {
+		result.Warnings = append(result.Warnings, "No client folder with options.txt is found")
+		return result
+	}
+	result.Installation = inst.Label()
+	if !own {
+		result.Warnings = append(result.Warnings,
+			fmt.Sprintf("World is not saved by a client with options.txt, options.txt of %s is used", inst.Label()))
+	}
+	result.Options = opts
+
+	source := func(key string, def interface{}) string {
+		if v, ok := opts.Values[key]; ok {
+			return fmt.Sprintf("%s:%s in options.txt", key, v)
+		}
+		return fmt.Sprintf("default %s %v, not in options.txt", key, def)
+	}
+
+	result.Radius = opts.RenderDistance * 16
+	result.Filled = append(result.Filled, &matchedParam{
+		Param:  "radius",
+		Value:  result.Radius,
+		Source: source("renderDistance", mc.DefaultRenderDistance) + ", 16 blocks per chunk",
+	})
+
+	class := findClass(getTypes().Camera, camera.Name)
+	if class == nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Camera %s is not in catalog", camera.Name))
+		return result
+	}
+	params := map[string]interface{}{}
+	if old, ok := camera.Params.(map[string]interface{}); ok {
+		for k, v := range old {
+			params[k] = v
+		}
+	}
+	fov := pbrtFOV(opts.FOV, width, height)
+	fovSource := source("fov", mc.DefaultFOV)
+	if fov != opts.FOV {
+		fovSource += ", horizontal since image is taller than wide"
+	}
+	found := false
+	for _, param := range class.InitFunc.Params {
+		if !isFOVParam(param.Name) {
+			continue
+		}
+		found = true
+		value, err := paramValue(param, fov)
+		if err != nil {
+			result.Warnings = append(result.Warnings,
+				fmt.Sprintf("Camera %s param %s can not take fov: %s", camera.Name, param.Name, err))
+			continue
+		}
+		params[param.Name] = value
+		result.Filled = append(result.Filled, &matchedParam{
+			Class:  camera.Name,
+			Param:  param.Name,
+			Value:  value,
+			Source: fovSource,
+			ByName: true,
+		})
+	}
+	if !found {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Camera %s has no fov param", camera.Name))
+	}
+	result.Camera.Params = params
+	return result
+}
+
+// gameViewHandler fill camera in body and radius like the game view,
+// width and height of image are in query
+func gameViewHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	world := findWorld(query.Get("world"))
+	if world == nil {
+		log.Println("app.gameViewHandler: world not found")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	width, _ := strconv.Atoi(query.Get("width"))
+	height, _ := strconv.Atoi(query.Get("height"))
+	var camera mcwdrv.Class
+	if err := json.NewDecoder(r.Body).Decode(&camera); err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	bytes, err := json.Marshal(matchGameView(world, camera, width, height))
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprint(w, string(bytes))
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image/png"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/PbrtCraft/pbrtcraftdrv/mc"
+)
+
+// worldIconHandler serves icon of world, or a map around spawn if the world
+// has no icon. Responses have Last-Modified so browsers revalidate them,
+// and thumbnails are answered 304 before they are drawn.
+func worldIconHandler(w http.ResponseWriter, r *http.Request) {
+	world := findWorld(r.URL.Query().Get("world"))
+	if world == nil {
+		log.Println("app.worldIconHandler: world", r.URL.Query().Get("world"), "not found")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if fn := world.IconPath(); fn != "" {
+		f, err := os.Open(fn)
+		if err == nil {
+			defer f.Close()
+			var info os.FileInfo
+			if info, err = f.Stat(); err == nil {
+				http.ServeContent(w, r, world.Icon, info.ModTime(), f)
+				return
+			}
+		}
+		// Removed since last scan, fall back to thumbnail
+		log.Println("app.worldIconHandler:", err)
+	}
+
+	if !world.Renderable {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	m, err := getMapRenderer(world, mc.DimensionOverworld)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	// Regions are checked before drawing, so unchanged thumbnails are not drawn again
+	modTime, err := m.ThumbnailModTime(world.Spawn[0], world.Spawn[2])
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if modTime.IsZero() {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if notModified(r, modTime) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	data, modTime, err := worldThumbnail(r.Context(), world, m, modTime)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if data == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	http.ServeContent(w, r, "", modTime, bytes.NewReader(data))
+}
+
+// notModified check whether the response of request r was not modified since
+// If-Modified-Since, so it can be answered 304 before it is drawn
+func notModified(r *http.Request, modTime time.Time) bool {
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	return err == nil && !modTime.IsZero() && !modTime.Truncate(time.Second).After(since)
+}
+
+// thumbnail is png of a world thumbnail, nil if no chunk around spawn is generated
+type thumbnail struct {
+	modTime time.Time
+	data    []byte
+}
+
+// thumbnails cache thumbnails by world path, until their regions are modified
+var (
+	thumbnails      = map[string]*thumbnail{}
+	thumbnailsMutex sync.Mutex
+)
+
+// worldThumbnail return png of map m around spawn and when its regions were
+// modified, drawn again if modTime is not the one cached
+func worldThumbnail(ctx context.Context, world *mc.World, m *mc.MapRenderer, modTime time.Time) ([]byte, time.Time, error) {
+	thumbnailsMutex.Lock()
+	cached := thumbnails[world.Path]
+	thumbnailsMutex.Unlock()
+	if cached != nil && cached.modTime.Equal(modTime) {
+		return cached.data, cached.modTime, nil
+	}
+
+	img, modTime, err := m.Thumbnail(ctx, world.Spawn[0], world.Spawn[2])
+	if err != nil {
+		return nil, modTime, fmt.Errorf("app.worldThumbnail: %s", err)
+	}
+	t := &thumbnail{modTime: modTime}
+	if img != nil {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, modTime, fmt.Errorf("app.worldThumbnail: %s", err)
+		}
+		t.data = buf.Bytes()
+	}
+	thumbnailsMutex.Lock()
+	thumbnails[world.Path] = t
+	thumbnailsMutex.Unlock()
+	return t.data, modTime, nil
+}
+
+// dropThumbnails drop thumbnails of worlds not in worlds, like removed ones
+func dropThumbnails(worlds []*mc.World) {
+	paths := map[string]bool{}
+	for _, world := range worlds {
+		paths[world.Path] = true
+	}
+	thumbnailsMutex.Lock()
+	defer thumbnailsMutex.Unlock()
+	for key := range thumbnails {
+		if !paths[key] {
+			delete(thumbnails, key)
+		}
+	}
+}
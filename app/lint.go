@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/PbrtCraft/pbrtcraftdrv/parsepy"
+)
+
+// runLint is the lint subcommand, it return exit code:
+// 0 for no problem, 1 if any problem found, 2 on error
+func runLint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	registry := flags.String("registry", defaultTypeRegistry,
+		"Name of factory dict to check, empty to skip")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s lint [flags] file.py|dir...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	files := []string{}
+	for _, root := range flags.Args() {
+		err := filepath.Walk(root, func(fn string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && strings.HasSuffix(fn, ".py") {
+				files = append(files, fn)
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+
+	count := 0
+	for _, fn := range files {
+		problems, err := parsepy.Lint(fn, *registry)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+		count += len(problems)
+	}
+
+	if count > 0 {
+		fmt.Fprintf(os.Stderr, "%d problem(s) found in %d file(s)\n", count, len(files))
+		return 1
+	}
+	return 0
+}
@@ -34,7 +34,7 @@ func listLogHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fmt.Fprintf(w, string(bs))
+	fmt.Fprint(w, string(bs))
 }
 
 func getLogHandler(w http.ResponseWriter, r *http.Request) {
@@ -53,7 +53,7 @@ func getLogHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fmt.Fprintf(w, logStr)
+	fmt.Fprint(w, logStr)
 }
 
 func deleteLogHandler(w http.ResponseWriter, r *http.Request) {
@@ -8,9 +8,11 @@ import (
 	"html/template"
 	"log"
 	"net/http"
+	"os"
 	"path"
 
 	"github.com/PbrtCraft/pbrtcraftdrv/filetree"
+	"github.com/PbrtCraft/pbrtcraftdrv/mc"
 	"github.com/PbrtCraft/pbrtcraftdrv/mcwdrv"
 )
 
@@ -78,6 +80,7 @@ func renderHandler(w http.ResponseWriter, r *http.Request) {
 	var t struct {
 		World       string         `json:"world"`
 		Player      string         `json:"player"`
+		Target      *renderTarget  `json:"target"`
 		Width       int            `json:"width,string"`
 		Height      int            `json:"height,string"`
 		Sample      int            `json:"sample,string"`
@@ -85,6 +88,12 @@ func renderHandler(w http.ResponseWriter, r *http.Request) {
 		Method      mcwdrv.Class   `json:"method"`
 		Camera      mcwdrv.Class   `json:"camera"`
 		Phenomenons []mcwdrv.Class `json:"phenomenons"`
+
+		ResourcePacks []string `json:"resource_packs"` // Paths, highest priority first
+		AssetsVersion string   `json:"assets_version"` // Client version to take textures from
+
+		// Render world even if mc2pbrt does not support its version
+		Force bool `json:"force"`
 	}
 	err := decoder.Decode(&t)
 	if err != nil {
@@ -104,10 +113,71 @@ func renderHandler(w http.ResponseWriter, r *http.Request) {
 	rc.Resolution.Width = t.Width
 	rc.Resolution.Height = t.Height
 
+	// Checked before extracting assets, Compile checks again
+	if mcwDriver.GetStatus() != mcwdrv.StatusIdle {
+		log.Println(mcwdrv.ErrDriverNotIdel)
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprintf(w, "A render is running")
+		return
+	}
+
+	if err := checkCompatibility(t.World, t.Force); err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	rc.ResourcePacks, err = resolveResourcePacks(t.ResourcePacks)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	if t.Target != nil {
+		if t.Target.Dimension == "" {
+			t.Target.Dimension = mc.DimensionOverworld
+		}
+		rc.Player = ""
+		rc.Target = (*mcwdrv.Target)(t.Target)
+		if err := validateTarget(t.World, rc.Target); err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, err.Error())
+			return
+		}
+	} else if t.Player == "" {
+		log.Println("app.renderHandler: no player or target")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "Select a player or a target")
+		return
+	}
+
+	// Assets are kept locked until the render starts,
+	// so they are not extracted again before it reads them
+	assetsMutex.Lock()
+	defer assetsMutex.Unlock()
+	if t.AssetsVersion != "" {
+		rc.Assets, err = versionAssets(t.AssetsVersion, false)
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, err.Error())
+			return
+		}
+	}
+
 	log.Println("PATH:", t.World)
 
 	err = mcwDriver.Compile(rc)
-	if err != nil {
+	if err == mcwdrv.ErrDriverNotIdel {
+		log.Println(err)
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprintf(w, "A render is running")
+		return
+	} else if err != nil {
 		log.Println(err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
@@ -132,7 +202,7 @@ func getfilesHandler(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	fmt.Fprintf(w, string(bytes))
+	fmt.Fprint(w, string(bytes))
 }
 
 func statusHandler(w http.ResponseWriter, r *http.Request) {
@@ -145,6 +215,8 @@ func statusHandler(w http.ResponseWriter, r *http.Request) {
 		tmp.DriverStatus = "idle"
 	case mcwdrv.StatusReady:
 		tmp.DriverStatus = "ready"
+	case mcwdrv.StatusSnapshot:
+		tmp.DriverStatus = "snapshot"
 	case mcwdrv.StatusMc2pbrt:
 		tmp.DriverStatus = "mc2pbrt"
 	case mcwdrv.StatusPbrt:
@@ -158,7 +230,7 @@ func statusHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fmt.Fprintf(w, string(bs))
+	fmt.Fprint(w, string(bs))
 }
 
 func imgHandler(w http.ResponseWriter, r *http.Request) {
@@ -167,7 +239,7 @@ func imgHandler(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	fmt.Fprintf(w, imgBase64)
+	fmt.Fprint(w, imgBase64)
 }
 
 func closeHandler(w http.ResponseWriter, r *http.Request) {
@@ -176,6 +248,10 @@ func closeHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "lint" {
+		os.Exit(runLint(os.Args[2:]))
+	}
+
 	var err error
 	appconfFilenamePtr := flag.String("appconf", "appconfig.yaml", "App Config filename")
 	flag.Parse()
@@ -188,19 +264,41 @@ func main() {
 	}
 	log.Println("Start init app config...DONE")
 
-	log.Println("Start init srv worlds....")
-	if appconf.Minecraft.Directory == "" {
-		log.Println("init client worlds...")
-		err = initClientWorlds()
-	} else {
-		log.Println("init single world...")
-		err = initSingleWorld(appconf.Minecraft.Directory)
+	sources, err := initWorldSources(appconf.Minecraft.Sources, appconf.Minecraft.Directory, appconf.DataDir,
+		appconf.Minecraft.SearchRoots)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	err = initNameResolver(appconf.Minecraft.UUID, sources, appconf.DataDir)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	err = initMatchWorld(appconf.MatchWorld)
+	if err != nil {
+		log.Println(err)
+		return
 	}
+
+	err = initBookmarks(appconf.DataDir)
 	if err != nil {
 		log.Println(err)
 		return
 	}
-	log.Println("Start init srv worlds...DONE")
+
+	err = initResourcePacks(appconf.DataDir)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	initAssets(appconf.MWCDriver.Workdir)
+
+	log.Println("Start finding worlds in background...")
+	initWorlds(appconf.Minecraft.WatchInterval)
 
 	log.Println("Start reading python types...")
 	err = initTypes(appconf.PythonFile)
@@ -228,11 +326,35 @@ func main() {
 	mux.HandleFunc("/getstatus", statusHandler)
 	mux.HandleFunc("/getimg", imgHandler)
 	mux.HandleFunc("/gettype", typesHandler)
+	mux.HandleFunc("/gettype/schema", typeSchemasHandler)
+	mux.HandleFunc("/gettype/status", typeStatusHandler)
+	mux.HandleFunc("/gettype/diff", typeDiffHandler)
 	mux.HandleFunc("/getworld", worldsHandler)
+	mux.HandleFunc("/world/import", importHandler)
+	mux.HandleFunc("/world/rescan", rescanHandler)
+	mux.HandleFunc("/world/status", worldStatusHandler)
+	mux.HandleFunc("/world/icon", worldIconHandler)
+	mux.HandleFunc("/resourcepacks", resourcePacksHandler)
+	mux.HandleFunc("/resourcepacks/icon", resourcePackIconHandler)
+	mux.HandleFunc("/resourcepacks/upload", uploadResourcePackHandler)
+	mux.HandleFunc("/assets/versions", clientVersionsHandler)
+	mux.HandleFunc("/assets/extract", extractAssetsHandler)
+	mux.HandleFunc("/world/coverage", coverageHandler)
+	mux.HandleFunc("/world/inventory", inventoryHandler)
+	mux.HandleFunc("/world/phenomenons", matchWorldHandler)
+	mux.HandleFunc("/world/view", gameViewHandler)
+	mux.HandleFunc("/bookmarks/list", listBookmarksHandler)
+	mux.HandleFunc("/bookmarks/save", saveBookmarkHandler)
+	mux.HandleFunc("/bookmarks/delete", deleteBookmarkHandler)
+	mux.HandleFunc("/bookmarks/export", exportBookmarksHandler)
+	mux.HandleFunc("/bookmarks/import", importBookmarksHandler)
+	mux.HandleFunc("/map/tile", mapTileHandler)
+	mux.HandleFunc("/map/column", mapColumnHandler)
 	mux.HandleFunc("/getfiles", getfilesHandler)
 	mux.HandleFunc("/render", renderHandler)
 	mux.HandleFunc("/stop", stopHandler)
 	mux.HandleFunc("/close", closeHandler)
+	mux.HandleFunc("/events", eventsHandler)
 
 	mux.HandleFunc("/log", logHandler)
 	mux.HandleFunc("/log/list", listLogHandler)
@@ -249,6 +371,7 @@ func main() {
 	port := appconf.Srv.Port
 	log.Printf("Start listen at :%s...", port)
 	srv = http.Server{Addr: ":" + port, Handler: mux}
+	srv.RegisterOnShutdown(events.close)
 	err = srv.ListenAndServe()
 	if err != nil && err != http.ErrServerClosed {
 		log.Println(err)
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PbrtCraft/pbrtcraftdrv/mc"
+)
+
+// Map tiles are mapTileSize pixels wide, at zoom z a pixel is 2^-z blocks.
+// A tile at mapMinZoom covers 1024 chunks, which mc.MapCacheChunks can hold
+// for a few tiles, tiles of lower zoom would evict their own chunks.
+const (
+	mapTileSize = 256
+	mapMinZoom  = -1
+	mapMaxZoom  = 0
+)
+
+var (
+	mapRenderersMutex sync.Mutex
+	mapRenderers      = map[string]*mc.MapRenderer{}
+)
+
+// mapTile is a png tile, kept until regions in it are modified
+type mapTile struct {
+	key     string
+	modTime time.Time
+	data    []byte
+}
+
+// mapTileCacheSize is the most tiles kept, the least recently used are dropped
+const mapTileCacheSize = 512
+
+var (
+	mapTilesMutex  sync.Mutex
+	mapTiles       = map[string]*list.Element{} // Values are *mapTile
+	mapTilesRecent = list.New()                 // Most recently used first
+)
+
+// cachedMapTile return png of tile key drawn when its regions were modified
+// at modTime, nil if it is not cached
+func cachedMapTile(key string, modTime time.Time) []byte {
+	mapTilesMutex.Lock()
+	defer mapTilesMutex.Unlock()
+	e, ok := mapTiles[key]
+	if !ok || !e.Value.(*mapTile).modTime.Equal(modTime) {
+		return nil
+	}
+	mapTilesRecent.MoveToFront(e)
+	return e.Value.(*mapTile).data
+}
+
+func cacheMapTile(tile *mapTile) {
+	mapTilesMutex.Lock()
+	defer mapTilesMutex.Unlock()
+	if e, ok := mapTiles[tile.key]; ok {
+		e.Value = tile
+		mapTilesRecent.MoveToFront(e)
+		return
+	}
+	mapTiles[tile.key] = mapTilesRecent.PushFront(tile)
+	for mapTilesRecent.Len() > mapTileCacheSize {
+		e := mapTilesRecent.Back()
+		mapTilesRecent.Remove(e)
+		delete(mapTiles, e.Value.(*mapTile).key)
+	}
+}
+
+// getMapRenderer return the map renderer of world and dimension,
+// renderers keep columns of chunks between tiles
+func getMapRenderer(world *mc.World, dim string) (*mc.MapRenderer, error) {
+	if _, err := mc.DimensionDir(world.Path, dim); err != nil {
+		return nil, fmt.Errorf("app.getMapRenderer: %s", err)
+	}
+	key := world.Path + "\x00" + dim
+	mapRenderersMutex.Lock()
+	defer mapRenderersMutex.Unlock()
+	m, ok := mapRenderers[key]
+	if !ok {
+		m = mc.NewMapRenderer(world, dim)
+		mapRenderers[key] = m
+	}
+	return m, nil
+}
+
+// dropMapRenderers drop renderers and tiles of worlds not in worlds, like removed ones
+func dropMapRenderers(worlds []*mc.World) {
+	paths := map[string]bool{}
+	for _, world := range worlds {
+		paths[world.Path] = true
+	}
+	mapRenderersMutex.Lock()
+	for key := range mapRenderers {
+		if !paths[strings.SplitN(key, "\x00", 2)[0]] {
+			delete(mapRenderers, key)
+		}
+	}
+	mapRenderersMutex.Unlock()
+
+	mapTilesMutex.Lock()
+	defer mapTilesMutex.Unlock()
+	for key, e := range mapTiles {
+		if !paths[strings.SplitN(key, "\x00", 2)[0]] {
+			mapTilesRecent.Remove(e)
+			delete(mapTiles, key)
+		}
+	}
+}
+
+func parseMapQuery(r *http.Request, keys ...string) (*mc.MapRenderer, []int, error) {
+	query := r.URL.Query()
+	world := findWorld(query.Get("world"))
+	if world == nil {
+		return nil, nil, fmt.Errorf("app.parseMapQuery: world %s not found", query.Get("world"))
+	}
+	m, err := getMapRenderer(world, query.Get("dim"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("app.parseMapQuery: %s", err)
+	}
+	values := make([]int, len(keys))
+	for i, key := range keys {
+		values[i], err = strconv.Atoi(query.Get(key))
+		if err != nil {
+			return nil, nil, fmt.Errorf("app.parseMapQuery: bad %s %s", key, query.Get(key))
+		}
+	}
+	return m, values, nil
+}
+
+// mapTileHandler serves png tile x, y at zoom z, tile y is along block Z.
+// Tiles are drawn again only after their regions are modified.
+func mapTileHandler(w http.ResponseWriter, r *http.Request) {
+	m, values, err := parseMapQuery(r, "z", "x", "y")
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	zoom, tx, ty := values[0], values[1], values[2]
+	if zoom < mapMinZoom || zoom > mapMaxZoom {
+		log.Println("app.mapTileHandler: bad zoom", zoom)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	scale := 1 << uint(-zoom)
+	shade := r.URL.Query().Get("shade") != "0"
+	x0, z0 := tx*mapTileSize*scale, ty*mapTileSize*scale
+
+	modTime, err := m.RegionsModTime(x0, z0, mapTileSize*scale)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Cache-Control", "max-age=60")
+	if notModified(r, modTime) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	query := r.URL.Query()
+	key := fmt.Sprintf("%s\x00%s\x00%d/%d/%d/%t", query.Get("world"), query.Get("dim"), zoom, tx, ty, shade)
+	data := cachedMapTile(key, modTime)
+	if data == nil {
+		// Drawing stops when the tile is panned away and the request is cancelled
+		img, err := m.Render(r.Context(), x0, z0, mapTileSize, scale, shade)
+		if err != nil {
+			if r.Context().Err() == nil {
+				log.Println(err)
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		data = buf.Bytes()
+		cacheMapTile(&mapTile{key: key, modTime: modTime, data: data})
+	}
+	w.Header().Set("Content-Type", "image/png")
+	http.ServeContent(w, r, "", modTime, bytes.NewReader(data))
+}
+
+// mapColumnHandler return top block at x, z, used to pick render center
+func mapColumnHandler(w http.ResponseWriter, r *http.Request) {
+	m, values, err := parseMapQuery(r, "x", "z")
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	column, err := m.Column(values[0], values[1])
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(column)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprint(w, string(bytes))
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+
+	"github.com/PbrtCraft/pbrtcraftdrv/mc"
+	"github.com/PbrtCraft/pbrtcraftdrv/mcwdrv"
+	"github.com/PbrtCraft/pbrtcraftdrv/parsepy"
+)
+
+// matchWorldRule fill params of a phenomenon class with time and weather of world
+type matchWorldRule struct {
+	Class  string            `yaml:"class" json:"class"`   // Phenomenon class
+	When   string            `yaml:"when" json:"when"`     // Weather the class is added, default always
+	Params map[string]string `yaml:"params" json:"params"` // Param name to world value
+}
+
+// Weathers a rule applies in
+const (
+	matchAlways     = "always"
+	matchClear      = "clear"
+	matchRaining    = "raining"
+	matchThundering = "thundering"
+)
+
+// worldValues compute values of world which can fill params
+var worldValues = map[string]func(w *mc.World) interface{}{
+	// Ticks of the day, 0 ~ 23999
+	"day_time": func(w *mc.World) interface{} {
+		return float64(((w.DayTime % mc.TicksPerDay) + mc.TicksPerDay) % mc.TicksPerDay)
+	},
+	// Hour of the clock, 0 ~ 24
+	"hour": func(w *mc.World) interface{} { return mc.DayHour(w.DayTime) },
+	// Degrees of sun from noon, 90 is sunset
+	"sun_angle":  func(w *mc.World) interface{} { return mc.CelestialAngle(w.DayTime) * 360 },
+	"raining":    func(w *mc.World) interface{} { return w.Raining },
+	"thundering": func(w *mc.World) interface{} { return w.Raining && w.Thundering },
+}
+
+var matchWorldRules []*matchWorldRule
+
+func initMatchWorld(rules []*matchWorldRule) error {
+	for _, rule := range rules {
+		switch rule.When {
+		case "":
+			rule.When = matchAlways
+		case matchAlways, matchClear, matchRaining, matchThundering:
+		default:
+			return fmt.Errorf("app.initMatchWorld: %s: unknown weather %s", rule.Class, rule.When)
+		}
+		for param, value := range rule.Params {
+			if _, ok := worldValues[value]; !ok {
+				return fmt.Errorf("app.initMatchWorld: %s.%s: unknown world value %s", rule.Class, param, value)
+			}
+		}
+	}
+	matchWorldRules = rules
+	return nil
+}
+
+// matchedParam is a param filled by world
+type matchedParam struct {
+	Class  string      `json:"class"`
+	Param  string      `json:"param"`
+	Value  interface{} `json:"value"`
+	Source string      `json:"source"`  // World value
+	ByName bool        `json:"by_name"` // Param is named as the world value, not in config
+}
+
+// matchWorldResult is phenomenons filled by world
+type matchWorldResult struct {
+	Phenomenons []mcwdrv.Class  `json:"phenomenons"`
+	Filled      []*matchedParam `json:"filled"`
+	Warnings    []string        `json:"warnings"`
+}
+
+func ruleApplies(rule *matchWorldRule, w *mc.World) bool {
+	switch rule.When {
+	case matchClear:
+		return !w.Raining
+	case matchRaining:
+		return w.Raining
+	case matchThundering:
+		return w.Raining && w.Thundering
+	}
+	return true
+}
+
+func findClass(classes []*parsepy.Class, name string) *parsepy.Class {
+	for _, class := range classes {
+		if class.Name == name {
+			return class
+		}
+	}
+	return nil
+}
+
+func findParam(class *parsepy.Class, name string) *parsepy.Param {
+	for _, param := range class.InitFunc.Params {
+		if param.Name == name {
+			return param
+		}
+	}
+	return nil
+}
+
+// paramValue convert a world value, float64 or bool, to the type of param
+func paramValue(param *parsepy.Param, value interface{}) (interface{}, error) {
+	if def, err := parsepy.ParseLiteral(param.DefaultValue); err == nil {
+		if _, ok := def.(bool); ok {
+			switch v := value.(type) {
+			case bool:
+				return v, nil
+			case float64:
+				return v != 0, nil
+			}
+		}
+	}
+
+	f, ok := value.(float64)
+	if b, isBool := value.(bool); isBool {
+		ok = true
+		if b {
+			f = 1
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("app.paramValue: bad value %v", value)
+	}
+	switch param.Type {
+	case parsepy.ParamTypeInt:
+		return int64(math.Round(f)), nil
+	case parsepy.ParamTypeFloat:
+		return f, nil
+	}
+	return nil, fmt.Errorf("app.paramValue: %s is %s", param.Name, param.Type)
+}
+
+// matchWorld add or remove phenomenons of rules by weather of world,
+// and fill their params from the world
+func matchWorld(w *mc.World, phenomenons []mcwdrv.Class) *matchWorldResult {
+	classes := getTypes().Phenomenon
+	result := &matchWorldResult{
+		Phenomenons: []mcwdrv.Class{},
+		Filled:      []*matchedParam{},
+		Warnings:    []string{},
+	}
+
+	// Rules of classes not in catalog are skipped
+	rules := map[string][]*matchWorldRule{}
+	for _, rule := range matchWorldRules {
+		class := findClass(classes, rule.Class)
+		if class == nil {
+			result.Warnings = append(result.Warnings,
+				fmt.Sprintf("Class %s in match_world is not a phenomenon", rule.Class))
+			continue
+		}
+		for param := range rule.Params {
+			if findParam(class, param) == nil {
+				result.Warnings = append(result.Warnings,
+					fmt.Sprintf("Phenomenon %s has no param %s in match_world", rule.Class, param))
+			}
+		}
+		rules[rule.Class] = append(rules[rule.Class], rule)
+	}
+
+	// Phenomenons of rules are removed if no rule applies, like rain in clear weather
+	for _, ph := range phenomenons {
+		keep := len(rules[ph.Name]) == 0
+		for _, rule := range rules[ph.Name] {
+			keep = keep || ruleApplies(rule, w)
+		}
+		if keep {
+			result.Phenomenons = append(result.Phenomenons, ph)
+		}
+	}
+	for _, rule := range matchWorldRules {
+		if rules[rule.Class] == nil || !ruleApplies(rule, w) {
+			continue
+		}
+		added := false
+		for _, ph := range result.Phenomenons {
+			added = added || ph.Name == rule.Class
+		}
+		if !added {
+			result.Phenomenons = append(result.Phenomenons, mcwdrv.Class{Name: rule.Class})
+		}
+	}
+
+	for i, ph := range result.Phenomenons {
+		class := findClass(classes, ph.Name)
+		if class == nil {
+			continue
+		}
+		params := map[string]interface{}{}
+		if old, ok := ph.Params.(map[string]interface{}); ok {
+			for k, v := range old {
+				params[k] = v
+			}
+		}
+		for _, param := range class.InitFunc.Params {
+			source, byName := "", false
+			for _, rule := range rules[ph.Name] {
+				if v, ok := rule.Params[param.Name]; ok {
+					source = v
+				}
+			}
+			if _, ok := worldValues[param.Name]; source == "" && ok {
+				source, byName = param.Name, true
+			}
+			if source == "" {
+				if _, ok := params[param.Name]; !ok {
+					if v, err := param.Value(); err == nil {
+						params[param.Name] = v
+					}
+				}
+				continue
+			}
+
+			value, err := paramValue(param, worldValues[source](w))
+			if err != nil {
+				result.Warnings = append(result.Warnings,
+					fmt.Sprintf("Phenomenon %s param %s can not take %s: %s", ph.Name, param.Name, source, err))
+				continue
+			}
+			params[param.Name] = value
+			result.Filled = append(result.Filled, &matchedParam{
+				Class:  ph.Name,
+				Param:  param.Name,
+				Value:  value,
+				Source: source,
+				ByName: byName,
+			})
+		}
+		result.Phenomenons[i].Params = params
+	}
+	return result
+}
+
+// matchWorldHandler fill phenomenons in body with time and weather of world
+func matchWorldHandler(w http.ResponseWriter, r *http.Request) {
+	world := findWorld(r.URL.Query().Get("world"))
+	if world == nil {
+		log.Println("app.matchWorldHandler: world not found")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var phenomenons []mcwdrv.Class
+	if err := json.NewDecoder(r.Body).Decode(&phenomenons); err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	bytes, err := json.Marshal(matchWorld(world, phenomenons))
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprint(w, string(bytes))
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+
+	"github.com/PbrtCraft/pbrtcraftdrv/mc"
+)
+
+// resourcePacksDir is where uploaded resource packs are kept
+var resourcePacksDir string
+
+// maxResourcePackUpload is the largest resource pack can be uploaded
+const maxResourcePackUpload = 1 << 30
+
+// resourcePackGroup is resource packs in a folder
+type resourcePackGroup struct {
+	Label string             `json:"label"`
+	Dir   string             `json:"dir"`
+	Packs []*mc.ResourcePack `json:"packs"`
+	// Errors is folders and zips which are not readable packs
+	Errors []*mc.ResourcePackError `json:"errors"`
+}
+
+func initResourcePacks(dataDir string) error {
+	resourcePacksDir = path.Join(dataDir, "resourcepacks")
+	if err := os.MkdirAll(resourcePacksDir, os.ModePerm); err != nil {
+		return fmt.Errorf("app.initResourcePacks: %s", err)
+	}
+	return nil
+}
+
+// listResourcePacks return packs in resourcepacks of client installations and uploaded ones
+func listResourcePacks() ([]*resourcePackGroup, error) {
+	groups := []*resourcePackGroup{}
+	for _, s := range getWorldSources() {
+		if s.Type != sourceClient {
+			continue
+		}
+		for _, inst := range s.Installations {
+			label := s.Label
+			if len(s.Installations) > 1 {
+				label += ": " + inst.Label()
+			}
+			groups = append(groups, &resourcePackGroup{
+				Label: label,
+				Dir:   path.Join(inst.Dir, "resourcepacks"),
+			})
+		}
+	}
+	groups = append(groups, &resourcePackGroup{Label: "Uploaded", Dir: resourcePacksDir})
+
+	for _, g := range groups {
+		var err error
+		g.Packs, g.Errors, err = mc.ListResourcePacks(g.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("app.listResourcePacks: %s", err)
+		}
+	}
+	return groups, nil
+}
+
+// resolveResourcePacks check packs are listed, and return their paths in order
+func resolveResourcePacks(packPaths []string) ([]string, error) {
+	groups, err := listResourcePacks()
+	if err != nil {
+		return nil, fmt.Errorf("app.resolveResourcePacks: %s", err)
+	}
+	known := map[string]bool{}
+	for _, g := range groups {
+		for _, pack := range g.Packs {
+			known[pack.Path] = true
+		}
+	}
+
+	ret := []string{}
+	used := map[string]bool{}
+	for _, p := range packPaths {
+		if !known[p] {
+			return nil, fmt.Errorf("app.resolveResourcePacks: resource pack %s not found", p)
+		}
+		if used[p] {
+			return nil, fmt.Errorf("app.resolveResourcePacks: resource pack %s is used twice", p)
+		}
+		used[p] = true
+		ret = append(ret, p)
+	}
+	return ret, nil
+}
+
+func resourcePacksHandler(w http.ResponseWriter, r *http.Request) {
+	groups, err := listResourcePacks()
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(groups)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprint(w, string(bytes))
+}
+
+// resourcePackIconHandler serve pack.png of a listed resource pack
+func resourcePackIconHandler(w http.ResponseWriter, r *http.Request) {
+	packPath := r.URL.Query().Get("path")
+	if _, err := resolveResourcePacks([]string{packPath}); err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	data, modTime, err := mc.ReadResourcePackIcon(packPath)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(w, r, "", modTime, bytes.NewReader(data))
+}
+
+// uploadResourcePackHandler keep uploaded resource pack zip, if it has pack.mcmeta
+func uploadResourcePackHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxResourcePackUpload)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	fn := uniqueUploadPath(resourcePacksDir, header.Filename, ".zip", "pack")
+	out, err := os.Create(fn)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	_, err = io.Copy(out, file)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		_, err = mc.ReadResourcePack(fn)
+	}
+	if err != nil {
+		log.Println(err)
+		os.Remove(fn)
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+	log.Println("Upload resource pack", header.Filename, "to", fn)
+
+	resourcePacksHandler(w, r)
+}
@@ -0,0 +1,531 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PbrtCraft/pbrtcraftdrv/mc"
+	"github.com/PbrtCraft/pbrtcraftdrv/mcwdrv"
+)
+
+// Types of world source
+const (
+	sourceClient  = "client"  // Minecraft client or launcher folder, auto detected if path is empty
+	sourceServer  = "server"  // Server folder with world folders, or a world folder
+	sourceBackups = "backups" // Folder of backups, worlds may be nested
+)
+
+// worldSource is a place to find worlds
+type worldSource struct {
+	Label string `yaml:"label" json:"label"`
+	Type  string `yaml:"type" json:"type"`
+	Path  string `yaml:"path" json:"path"` // Client is auto detected if empty
+
+	Worlds []*mc.World   `yaml:"-" json:"worlds"`
+	Error  string        `yaml:"-" json:"error,omitempty"` // Source can not be read
+	Errors []*worldError `yaml:"-" json:"errors"`          // Worlds can not be read
+
+	// Compatibility of worlds with mc2pbrt by path
+	Compatibility map[string]string `yaml:"-" json:"compatibility"`
+
+	// Game folders of client source, like instances of launchers
+	Installations []*mc.Installation `yaml:"-" json:"installations"`
+	// Labels of installations by world path, for sources of many installations
+	Instances map[string]string `yaml:"-" json:"instances"`
+}
+
+// worldError is a world failed to load
+type worldError struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// worldStatus tells whether worlds are being scanned,
+// Version is increased after each scan
+type worldStatus struct {
+	Scanning  bool      `json:"scanning"`
+	Version   int       `json:"version"`
+	ScannedAt time.Time `json:"scanned_at"`
+}
+
+var (
+	worldsMutex       sync.RWMutex
+	worldSources      []*worldSource
+	worlds            []*mc.World // Worlds of all sources
+	worldStatusValue  worldStatus
+	worldRescanNeeded bool // Rescan requested while scanning
+
+	// Scans run by rescanWorlds, waited by rescanWorldsWait
+	worldScansStarted int
+	worldScansDone    int
+	worldScanDone     = sync.NewCond(&worldsMutex)
+)
+
+// importsDir is where uploaded world archives are unpacked
+var importsDir string
+
+// searchRoots are extra folders to find game folders in for auto detected clients
+var searchRoots []mc.LauncherRoot
+
+const (
+	// maxImportUpload is the largest world archive can be uploaded
+	maxImportUpload = 2 << 30
+	// backupsDepth is how deep worlds are searched in backups
+	backupsDepth = 3
+	// worldScanWorkers is how many worlds are read at the same time
+	worldScanWorkers = 4
+
+	defaultWorldWatchInterval = 5 * time.Second
+)
+
+// initWorldSources return sources from config, minecraft.directory is used as
+// a server source if no sources are configured. Uploaded archives are an extra
+// source in data_dir.
+func initWorldSources(sources []*worldSource, mcDir, dataDir string, roots []string) ([]*worldSource, error) {
+	for _, root := range roots {
+		searchRoots = append(searchRoots, mc.LauncherRoot{Dir: root})
+	}
+
+	if len(sources) == 0 {
+		if mcDir != "" {
+			sources = []*worldSource{{Label: "World", Type: sourceServer, Path: mcDir}}
+		} else {
+			sources = []*worldSource{{Label: "Client", Type: sourceClient}}
+		}
+	}
+	for _, s := range sources {
+		switch s.Type {
+		case sourceClient, sourceServer, sourceBackups:
+		default:
+			return nil, fmt.Errorf("app.initWorldSources: unknown source type %s", s.Type)
+		}
+		if s.Type != sourceClient && s.Path == "" {
+			return nil, fmt.Errorf("app.initWorldSources: source %s has no path", s.Label)
+		}
+		if s.Label == "" {
+			s.Label = s.Type
+		}
+	}
+
+	importsDir = path.Join(dataDir, "imports")
+	if err := os.MkdirAll(importsDir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("app.initWorldSources: %s", err)
+	}
+	sources = append(sources, &worldSource{Label: "Imported", Type: sourceBackups, Path: importsDir})
+
+	// Sources are listed before their worlds are found
+	worldsMutex.Lock()
+	worldSources = []*worldSource{}
+	for _, source := range sources {
+		s := *source
+		s.Worlds = []*mc.World{}
+		s.Errors = []*worldError{}
+		s.Compatibility = map[string]string{}
+		s.Installations = []*mc.Installation{}
+		s.Instances = map[string]string{}
+		worldSources = append(worldSources, &s)
+	}
+	worldsMutex.Unlock()
+	return sources, nil
+}
+
+// scan read worlds of source, newest played first
+func (s *worldSource) scan() error {
+	s.Worlds = []*mc.World{}
+	s.Errors = []*worldError{}
+	s.Compatibility = map[string]string{}
+	s.Installations = []*mc.Installation{}
+	s.Instances = map[string]string{}
+	var dirs []string
+	labels := map[string]string{}
+	var err error
+	switch s.Type {
+	case sourceClient:
+		roots := []mc.LauncherRoot{{Launcher: s.Label, Dir: s.Path}}
+		if s.Path == "" {
+			roots = append(mc.DefaultLauncherRoots(), searchRoots...)
+		}
+		s.Installations = mc.FindInstallations(roots)
+		if len(s.Installations) == 0 {
+			err = mc.ErrMinecraftClientNotFound
+			break
+		}
+		for _, inst := range s.Installations {
+			if _, serr := os.Stat(inst.Saves); serr != nil {
+				// Instance never saved a world
+				continue
+			}
+			found, ferr := mc.FindWorldDirs(inst.Saves, 1)
+			if ferr != nil {
+				// Worlds of other instances are still listed
+				log.Println("Find Worlds:", inst.Saves, ferr)
+				s.Errors = append(s.Errors, &worldError{Path: inst.Saves, Error: ferr.Error()})
+				continue
+			}
+			for _, dir := range found {
+				labels[dir] = inst.Label()
+			}
+			dirs = append(dirs, found...)
+		}
+	case sourceServer:
+		dirs, err = mc.FindWorldDirs(s.Path, 1)
+	case sourceBackups:
+		dirs, err = mc.FindWorldDirs(s.Path, backupsDepth)
+	}
+	if err != nil {
+		return fmt.Errorf("app.worldSource.scan: %s: %s", s.Label, err)
+	}
+
+	// A slow or broken world does not hold the others
+	loaded := make([]*mc.World, len(dirs))
+	errs := make([]error, len(dirs))
+	workers := make(chan struct{}, worldScanWorkers)
+	var wg sync.WaitGroup
+	for i, dir := range dirs {
+		wg.Add(1)
+		go func(i int, dir string) {
+			defer wg.Done()
+			workers <- struct{}{}
+			loaded[i], errs[i] = mc.NewWorld(dir)
+			<-workers
+		}(i, dir)
+	}
+	wg.Wait()
+
+	for i, dir := range dirs {
+		if errs[i] != nil {
+			log.Println("Read World:", dir, errs[i])
+			s.Errors = append(s.Errors, &worldError{Path: dir, Error: errs[i].Error()})
+			continue
+		}
+		s.Worlds = append(s.Worlds, loaded[i])
+		s.Compatibility[loaded[i].Path] = worldCompatibility(loaded[i])
+		if len(s.Installations) > 1 {
+			s.Instances[loaded[i].Path] = labels[dir]
+		}
+	}
+	sort.SliceStable(s.Worlds, func(i, j int) bool {
+		return s.Worlds[i].LastPlayed > s.Worlds[j].LastPlayed
+	})
+	return nil
+}
+
+// scanWorlds read worlds of all sources, errors are kept in sources.
+// Sources are copied, the ones being served are not modified.
+func scanWorlds(sources []*worldSource) {
+	scanned := []*worldSource{}
+	all := []*mc.World{}
+	for _, source := range sources {
+		s := *source
+		s.Error = ""
+		if err := s.scan(); err != nil {
+			log.Println(err)
+			s.Error = err.Error()
+		}
+		scanned = append(scanned, &s)
+		all = append(all, s.Worlds...)
+	}
+
+	worldsMutex.Lock()
+	worldSources = scanned
+	worlds = all
+	worldStatusValue.Version++
+	worldStatusValue.ScannedAt = time.Now()
+	status := worldStatusValue
+	worldsMutex.Unlock()
+	log.Println("Get", len(all), "world(s) from", len(sources), "source(s)")
+	dropMapRenderers(all)
+	dropThumbnails(all)
+
+	events.publish("worlds", status)
+}
+
+func getWorldSources() []*worldSource {
+	worldsMutex.RLock()
+	defer worldsMutex.RUnlock()
+	return worldSources
+}
+
+func getWorldStatus() worldStatus {
+	worldsMutex.RLock()
+	defer worldsMutex.RUnlock()
+	return worldStatusValue
+}
+
+// rescanWorlds scan worlds in background, a rescan requested while scanning
+// runs after the current one
+func rescanWorlds() {
+	worldsMutex.Lock()
+	if worldStatusValue.Scanning {
+		worldRescanNeeded = true
+		worldsMutex.Unlock()
+		return
+	}
+	worldStatusValue.Scanning = true
+	status := worldStatusValue
+	worldsMutex.Unlock()
+	events.publish("worlds", status)
+
+	go func() {
+		for {
+			worldsMutex.Lock()
+			worldScansStarted++
+			worldsMutex.Unlock()
+
+			scanWorlds(getWorldSources())
+
+			worldsMutex.Lock()
+			worldScansDone++
+			worldScanDone.Broadcast()
+			if !worldRescanNeeded {
+				worldStatusValue.Scanning = false
+				status := worldStatusValue
+				worldsMutex.Unlock()
+				events.publish("worlds", status)
+				return
+			}
+			worldRescanNeeded = false
+			worldsMutex.Unlock()
+		}
+	}()
+}
+
+// rescanWorldsWait rescan worlds like rescanWorlds, and wait until a scan
+// started after the call is done, so changes made before are found
+func rescanWorldsWait() {
+	worldsMutex.Lock()
+	want := worldScansStarted + 1
+	worldsMutex.Unlock()
+
+	rescanWorlds()
+
+	worldsMutex.Lock()
+	for worldScansDone < want {
+		worldScanDone.Wait()
+	}
+	worldsMutex.Unlock()
+}
+
+// watchWorlds rescan worlds when folders of sources or worlds change,
+// like new saves, joined players or saved level.dat
+func watchWorlds(interval time.Duration) {
+	type fileStamp struct {
+		modTime time.Time
+		size    int64
+	}
+	stamp := func() map[string]fileStamp {
+		files := []string{}
+		for _, s := range getWorldSources() {
+			switch s.Type {
+			case sourceClient:
+				for _, inst := range s.Installations {
+					files = append(files, inst.Saves)
+				}
+			default:
+				files = append(files, s.Path)
+			}
+			for _, world := range s.Worlds {
+				files = append(files, world.Path,
+					path.Join(world.Path, "level.dat"), path.Join(world.Path, "playerdata"))
+			}
+		}
+		ret := map[string]fileStamp{}
+		for _, fn := range files {
+			// Missing files have zero stamp, so they are found when created
+			if info, err := os.Stat(fn); err == nil {
+				ret[fn] = fileStamp{info.ModTime(), info.Size()}
+			}
+		}
+		return ret
+	}
+
+	version := -1
+	var last map[string]fileStamp
+	for range time.Tick(interval) {
+		status := getWorldStatus()
+		if status.Scanning {
+			continue
+		}
+		cur := stamp()
+		if status.Version != version {
+			// Worlds were rescanned, watch them from now
+			version, last = status.Version, cur
+			continue
+		}
+		changed := len(cur) != len(last)
+		for fn, s := range cur {
+			if last[fn] != s {
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		last = cur
+		log.Println("Worlds changed, rescanning...")
+		rescanWorlds()
+	}
+}
+
+// initWorlds find worlds in background, and watch them if interval is not negative
+func initWorlds(watchInterval int) {
+	rescanWorlds()
+	if watchInterval >= 0 {
+		interval := time.Duration(watchInterval) * time.Second
+		if interval == 0 {
+			interval = defaultWorldWatchInterval
+		}
+		go watchWorlds(interval)
+	}
+}
+
+func rescanHandler(w http.ResponseWriter, r *http.Request) {
+	rescanWorlds()
+	worldStatusHandler(w, r)
+}
+
+func worldStatusHandler(w http.ResponseWriter, r *http.Request) {
+	bytes, err := json.Marshal(getWorldStatus())
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprint(w, string(bytes))
+}
+
+func findWorld(worldPath string) *mc.World {
+	worldsMutex.RLock()
+	defer worldsMutex.RUnlock()
+	for _, world := range worlds {
+		if world.Path == worldPath {
+			return world
+		}
+	}
+	return nil
+}
+
+// worldCompatibility return compatibility of world with mc2pbrt
+func worldCompatibility(world *mc.World) string {
+	if !world.Renderable {
+		return mcwdrv.CompatUnsupported
+	}
+	return appconf.MWCDriver.DataVersion.Compatibility(world.DataVersion)
+}
+
+// checkCompatibility return error if mc2pbrt does not support version of world,
+// unless force is set. Worlds mc2pbrt can not read, like Bedrock ones, are never rendered.
+func checkCompatibility(worldPath string, force bool) error {
+	world := findWorld(worldPath)
+	if world == nil {
+		return fmt.Errorf("app.checkCompatibility: world %s not found", worldPath)
+	}
+	if !world.Renderable {
+		return fmt.Errorf("app.checkCompatibility: %s world %s can not be rendered by mc2pbrt", world.Edition, world.Name)
+	}
+	compat := worldCompatibility(world)
+	if compat != mcwdrv.CompatUnsupported {
+		return nil
+	}
+	if force {
+		log.Println("Force rendering unsupported world", worldPath, "of DataVersion", world.DataVersion)
+		return nil
+	}
+	return fmt.Errorf("app.checkCompatibility: DataVersion %d of world %s is not supported by mc2pbrt, force to render anyway",
+		world.DataVersion, world.Name)
+}
+
+func worldsHandler(w http.ResponseWriter, r *http.Request) {
+	worldsMutex.RLock()
+	bytes, err := json.Marshal(worldSources)
+	worldsMutex.RUnlock()
+	if err != nil {
+		log.Println(err)
+		fmt.Fprint(w, "[]")
+		return
+	}
+
+	fmt.Fprint(w, string(bytes))
+}
+
+var uploadNameRegexp = regexp.MustCompile(`[^A-Za-z0-9_\-. ]+`)
+
+// uniqueUploadPath return a path in dir not exists yet for uploaded file,
+// named after the file without extension, plus ext
+func uniqueUploadPath(dir, filename, ext, fallback string) string {
+	name := strings.TrimSuffix(path.Base(filepath.ToSlash(filename)), path.Ext(filename))
+	name = strings.TrimLeft(uploadNameRegexp.ReplaceAllString(name, "_"), ".")
+	if name == "" {
+		name = fallback
+	}
+	ret := path.Join(dir, name+ext)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(ret); os.IsNotExist(err) {
+			return ret
+		}
+		ret = path.Join(dir, name+"-"+strconv.Itoa(i)+ext)
+	}
+}
+
+// importHandler unpack uploaded world zip into imports folder
+func importHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportUpload)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	// Keep upload in a temp file, zip needs random access
+	tmp, err := ioutil.TempFile(importsDir, ".upload-")
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	size, err := io.Copy(tmp, file)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	dir := uniqueUploadPath(importsDir, header.Filename, "", "world")
+
+	err = mc.ExtractArchive(tmp, size, dir)
+	if err == nil {
+		var dirs []string
+		dirs, err = mc.FindWorldDirs(dir, backupsDepth-1)
+		if err == nil && len(dirs) == 0 {
+			err = fmt.Errorf("app.importHandler: no level.dat in %s", header.Filename)
+		}
+	}
+	if err != nil {
+		log.Println(err)
+		os.RemoveAll(dir)
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+	log.Println("Import world archive", header.Filename, "to", dir)
+
+	rescanWorldsWait()
+	worldsHandler(w, r)
+}
@@ -5,6 +5,10 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/PbrtCraft/pbrtcraftdrv/parsepy"
 )
@@ -13,44 +17,297 @@ type typeFile struct {
 	Camera     string `yaml:"camera"`     // Path to camera.py
 	Phenomenon string `yaml:"phenomenon"` // Path to phenomenon.py
 	Method     string `yaml:"method"`     // Path to method.py
+
+	// Name of factory dict which maps public name to class, default "type_map".
+	// Files without the dict expose every class.
+	Registry string `yaml:"registry"`
+
+	// How classes are read, "static" parses the source and "runtime" imports
+	// it with Python, falling back to "static" when Python fails
+	Backend string `yaml:"backend"`
+	Python  string `yaml:"python"` // Python interpreter for runtime backend
+
+	// Seconds between checking files for change, 0 for default, -1 to disable
+	WatchInterval int `yaml:"watch_interval"`
+}
+
+const (
+	defaultTypeRegistry = "type_map"
+	defaultTypePython   = "python3"
+
+	typeBackendStatic  = "static"
+	typeBackendRuntime = "runtime"
+)
+
+// typeCatalog is a immutable set of python classes,
+// it is replaced as a whole when python files are reloaded
+type typeCatalog struct {
+	Camera     []*parsepy.Class `json:"camera"`
+	Phenomenon []*parsepy.Class `json:"phenomenon"`
+	Method     []*parsepy.Class `json:"method"`
+
+	// Registered names whose class is not found, by kind
+	Missing map[string][]string `json:"missing"`
+
+	// Backend actually used for each kind
+	Backend map[string]string `json:"backend"`
 }
 
+// typeStatus is the result of the latest loading
+type typeStatus struct {
+	Version  int       `json:"version"`
+	LoadedAt time.Time `json:"loaded_at"`
+	Error    string    `json:"error,omitempty"`
+}
+
+const defaultTypeWatchInterval = 2 * time.Second
+
 var (
-	cameraTypeList     []*parsepy.Class
-	phenomenonTypeList []*parsepy.Class
-	methodTypeList     []*parsepy.Class
+	typeCatalogValue atomic.Value // *typeCatalog
+
+	typeStatusMutex sync.Mutex
+	typeStatusValue typeStatus
 )
 
 func initTypes(tf typeFile) error {
-	var err error
-	cameraTypeList, err = parsepy.GetClasses(tf.Camera)
+	catalog, err := loadTypes(tf)
 	if err != nil {
 		return fmt.Errorf("app.main.initTypes: %s", err)
 	}
+	typeCatalogValue.Store(catalog)
+	typeStatusValue = typeStatus{Version: 1, LoadedAt: time.Now()}
 
-	phenomenonTypeList, err = parsepy.GetClasses(tf.Phenomenon)
+	if tf.WatchInterval >= 0 {
+		interval := time.Duration(tf.WatchInterval) * time.Second
+		if interval == 0 {
+			interval = defaultTypeWatchInterval
+		}
+		go watchTypes(tf, interval)
+	}
+	return nil
+}
+
+func loadTypes(tf typeFile) (*typeCatalog, error) {
+	registry := tf.Registry
+	if registry == "" {
+		registry = defaultTypeRegistry
+	}
+
+	catalog := &typeCatalog{
+		Missing: map[string][]string{},
+		Backend: map[string]string{},
+	}
+	for _, kind := range []struct {
+		name     string
+		filename string
+		list     *[]*parsepy.Class
+	}{
+		{"camera", tf.Camera, &catalog.Camera},
+		{"phenomenon", tf.Phenomenon, &catalog.Phenomenon},
+		{"method", tf.Method, &catalog.Method},
+	} {
+		classes, backend, err := readClasses(tf, kind.filename)
+		if err != nil {
+			return nil, fmt.Errorf("app.loadTypes: %s", err)
+		}
+		catalog.Backend[kind.name] = backend
+		classes, missing, err := registerClasses(classes, kind.filename, registry)
+		if err != nil {
+			return nil, fmt.Errorf("app.loadTypes: %s", err)
+		}
+		*kind.list = classes
+		if len(missing) > 0 {
+			log.Printf("%s: class of %v not found in %s", kind.name, missing, kind.filename)
+			catalog.Missing[kind.name] = missing
+		}
+	}
+	return catalog, nil
+}
+
+// readClasses return classes in filename and the backend used
+func readClasses(tf typeFile, filename string) ([]*parsepy.Class, string, error) {
+	switch tf.Backend {
+	case "", typeBackendStatic:
+	case typeBackendRuntime:
+		classes, err := parsepy.GetClassesRuntime(typePython(tf), filename)
+		if err == nil {
+			return classes, typeBackendRuntime, nil
+		}
+		log.Println("Runtime backend failed, fall back to static:", err)
+	default:
+		return nil, "", fmt.Errorf("app.readClasses: unknown backend %s", tf.Backend)
+	}
+
+	classes, err := parsepy.GetClasses(filename)
 	if err != nil {
-		return fmt.Errorf("app.main.initTypes: %s", err)
+		return nil, "", fmt.Errorf("app.readClasses: %s", err)
+	}
+	return classes, typeBackendStatic, nil
+}
+
+func typePython(tf typeFile) string {
+	if tf.Python == "" {
+		return defaultTypePython
 	}
+	return tf.Python
+}
 
-	methodTypeList, err = parsepy.GetClasses(tf.Method)
+func registerClasses(classes []*parsepy.Class, filename, registry string) ([]*parsepy.Class, []string, error) {
+	dicts, err := parsepy.GetDicts(filename)
 	if err != nil {
-		return fmt.Errorf("app.main.initTypes: %s", err)
+		return nil, nil, fmt.Errorf("app.registerClasses: %s", err)
+	}
+	dict := parsepy.GetDict(dicts, registry)
+	if dict == nil {
+		return classes, nil, nil
 	}
+	exposed, missing := parsepy.Registry(classes, dict)
+	return exposed, missing, nil
+}
 
-	return nil
+func getTypes() *typeCatalog {
+	return typeCatalogValue.Load().(*typeCatalog)
+}
+
+func getTypeStatus() typeStatus {
+	typeStatusMutex.Lock()
+	defer typeStatusMutex.Unlock()
+	return typeStatusValue
+}
+
+// reloadTypes keeps the previous catalog if any file fails to parse
+func reloadTypes(tf typeFile) {
+	catalog, err := loadTypes(tf)
+
+	typeStatusMutex.Lock()
+	if err != nil {
+		log.Println("Reload python types:", err)
+		typeStatusValue.Error = err.Error()
+	} else {
+		typeCatalogValue.Store(catalog)
+		typeStatusValue = typeStatus{
+			Version:  typeStatusValue.Version + 1,
+			LoadedAt: time.Now(),
+		}
+		log.Println("Reload python types...DONE")
+	}
+	status := typeStatusValue
+	typeStatusMutex.Unlock()
+
+	events.publish("types", status)
+}
+
+func watchTypes(tf typeFile, interval time.Duration) {
+	type fileStamp struct {
+		modTime time.Time
+		size    int64
+	}
+	stamp := func() (map[string]fileStamp, bool) {
+		ret := map[string]fileStamp{}
+		for _, fn := range []string{tf.Camera, tf.Phenomenon, tf.Method} {
+			info, err := os.Stat(fn)
+			if err != nil {
+				// Editors may remove file before writing, check next time
+				return ret, false
+			}
+			ret[fn] = fileStamp{info.ModTime(), info.Size()}
+		}
+		return ret, true
+	}
+
+	last, _ := stamp()
+	for range time.Tick(interval) {
+		cur, ok := stamp()
+		if !ok {
+			continue
+		}
+		changed := false
+		for fn, s := range cur {
+			if last[fn] != s {
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		last = cur
+		log.Println("Python type files changed, reloading...")
+		reloadTypes(tf)
+	}
 }
 
 func typesHandler(w http.ResponseWriter, r *http.Request) {
+	bytes, err := json.MarshalIndent(getTypes(), "", " ")
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprint(w, string(bytes))
+}
+
+// typeDiffHandler report where static and runtime backends disagree
+func typeDiffHandler(w http.ResponseWriter, r *http.Request) {
+	ret := map[string][]*parsepy.Difference{}
+	for kind, filename := range map[string]string{
+		"camera":     appconf.PythonFile.Camera,
+		"phenomenon": appconf.PythonFile.Phenomenon,
+		"method":     appconf.PythonFile.Method,
+	} {
+		static, err := parsepy.GetClasses(filename)
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		runtime, err := parsepy.GetClassesRuntime(typePython(appconf.PythonFile), filename)
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, err)
+			return
+		}
+		ret[kind] = parsepy.Compare(static, runtime)
+	}
+
+	bytes, err := json.MarshalIndent(ret, "", " ")
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprint(w, string(bytes))
+}
+
+func typeStatusHandler(w http.ResponseWriter, r *http.Request) {
+	bytes, err := json.Marshal(getTypeStatus())
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprint(w, string(bytes))
+}
+
+func typeSchemasHandler(w http.ResponseWriter, r *http.Request) {
+	schemas := func(classes []*parsepy.Class) []*parsepy.Schema {
+		ret := []*parsepy.Schema{}
+		for _, class := range classes {
+			ret = append(ret, class.JSONSchema())
+		}
+		return ret
+	}
+	catalog := getTypes()
 	bytes, err := json.MarshalIndent(map[string]interface{}{
-		"camera":     cameraTypeList,
-		"phenomenon": phenomenonTypeList,
-		"method":     methodTypeList,
+		"camera":     schemas(catalog.Camera),
+		"phenomenon": schemas(catalog.Phenomenon),
+		"method":     schemas(catalog.Method),
 	}, "", " ")
 	if err != nil {
 		log.Println(err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	fmt.Fprintf(w, string(bytes))
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(bytes)
 }
@@ -1,74 +1,81 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"log"
-	"net/http"
 	"path"
+	"time"
 
 	"github.com/PbrtCraft/pbrtcraftdrv/mc"
 )
 
-var worlds []*mc.World
+type uuidConfig struct {
+	// Order of resolvers: usercache, offline, cache and http.
+	// Default is usercache, cache, offline
+	Resolvers []string `yaml:"resolvers"`
 
-func initSingleWorld(mcDir string) error {
-	world, err := mc.NewWorld(mcDir)
-	if err != nil {
-		return fmt.Errorf("app.main.initSingleWorld: %s", err)
-	}
-	worlds = []*mc.World{world}
-	return nil
+	UserCache    []string `yaml:"usercache"`     // Extra usercache.json files
+	OfflineNames []string `yaml:"offline_names"` // Names to match offline-mode UUIDs
+
+	HTTP struct {
+		BaseURL string `yaml:"base_url"` // Profile API, default is Mojang's
+		Timeout int    `yaml:"timeout"`  // Seconds, default is 5
+	} `yaml:"http"`
 }
 
-func initClientWorlds() error {
-	mcWorldsDir, err := mc.FindMinecraft()
-	if err != nil {
-		return fmt.Errorf("app.main.initClientWorlds: %s", err)
-	}
+var defaultUUIDResolvers = []string{"usercache", "cache", "offline"}
 
-	worldFolders, err := listWorlds(mcWorldsDir)
-	if err != nil {
-		return fmt.Errorf("app.main.initClientWorlds: %s", err)
-	}
+const defaultUUIDHTTPTimeout = 5 * time.Second
 
-	worlds = nil
-	for _, folder := range worldFolders {
-		mcDir := path.Join(mcWorldsDir, "saves", folder)
-		world, err := mc.NewWorld(mcDir)
-		if err != nil {
-			log.Println("Read World:", folder, err)
-			continue
-		}
-		worlds = append(worlds, world)
+func initNameResolver(conf uuidConfig, sources []*worldSource, dataDir string) error {
+	names := conf.Resolvers
+	if len(names) == 0 {
+		names = defaultUUIDResolvers
 	}
-	log.Println("Get", len(worlds), "world(s)")
-	return nil
-}
 
-func worldsHandler(w http.ResponseWriter, r *http.Request) {
-	bytes, err := json.Marshal(worlds)
+	cache, err := mc.NewCacheResolver(path.Join(dataDir, "usercache.json"))
 	if err != nil {
-		log.Println(err)
-		fmt.Fprint(w, "[]")
-		return
+		return fmt.Errorf("app.initNameResolver: %s", err)
 	}
+	chain := &mc.ChainResolver{Cache: cache}
 
-	fmt.Fprint(w, string(bytes))
-}
-
-func listWorlds(mcDir string) ([]string, error) {
-	saveDir := path.Join(mcDir, "saves")
-	folders, err := ioutil.ReadDir(saveDir)
-	if err != nil {
-		return nil, fmt.Errorf("app.listWorlds: %s", err)
-	}
-	ret := []string{}
-	for _, folder := range folders {
-		if folder.IsDir() {
-			ret = append(ret, folder.Name())
+	for _, name := range names {
+		switch name {
+		case "usercache":
+			files := []string{}
+			if clientDir, err := mc.FindMinecraft(); err == nil {
+				files = append(files, path.Join(clientDir, "usercache.json"))
+			}
+			for _, s := range sources {
+				switch s.Type {
+				case sourceClient:
+					if s.Path != "" {
+						files = append(files, path.Join(s.Path, "usercache.json"))
+					}
+				case sourceServer:
+					// Server keeps usercache.json next to its world folders
+					files = append(files, path.Join(s.Path, "usercache.json"),
+						path.Join(s.Path, "..", "usercache.json"))
+				}
+			}
+			files = append(files, conf.UserCache...)
+			chain.Resolvers = append(chain.Resolvers, &mc.UserCacheResolver{Files: files})
+		case "offline":
+			chain.Resolvers = append(chain.Resolvers, &mc.OfflineResolver{Names: conf.OfflineNames})
+		case "cache":
+			chain.Resolvers = append(chain.Resolvers, cache)
+		case "http":
+			timeout := time.Duration(conf.HTTP.Timeout) * time.Second
+			if timeout == 0 {
+				timeout = defaultUUIDHTTPTimeout
+			}
+			chain.Resolvers = append(chain.Resolvers, &mc.HTTPResolver{
+				BaseURL: conf.HTTP.BaseURL,
+				Timeout: timeout,
+			})
+		default:
+			return fmt.Errorf("app.initNameResolver: unknown resolver %s", name)
 		}
 	}
-	return ret, nil
+	mc.SetNameResolver(chain)
+	return nil
 }
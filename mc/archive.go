@@ -0,0 +1,115 @@
+package mc
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MaxArchiveSize is the largest uncompressed size of a world archive
+const MaxArchiveSize = 8 << 30
+
+// ExtractArchive unpack a zip world archive into dir,
+// entries escaping dir or larger than MaxArchiveSize in total are rejected
+func ExtractArchive(r io.ReaderAt, size int64, dir string) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("mc.ExtractArchive: %s", err)
+	}
+	if err := extractZip(zr, dir, nil); err != nil {
+		return fmt.Errorf("mc.ExtractArchive: %s", err)
+	}
+	return nil
+}
+
+// extractZip unpack entries matching filter into dir, all entries if filter is nil
+func extractZip(zr *zip.Reader, dir string, filter func(name string) bool) error {
+	files := []*zip.File{}
+	var total uint64
+	for _, f := range zr.File {
+		if filter != nil && !filter(f.Name) {
+			continue
+		}
+		total += f.UncompressedSize64
+		if total > MaxArchiveSize {
+			return fmt.Errorf("archive larger than %d bytes", uint64(MaxArchiveSize))
+		}
+		target := filepath.Join(dir, filepath.FromSlash(f.Name))
+		if target != filepath.Clean(dir) && !strings.HasPrefix(target, filepath.Clean(dir)+string(filepath.Separator)) {
+			return fmt.Errorf("bad entry %s", f.Name)
+		}
+		files = append(files, f)
+	}
+
+	for _, f := range files {
+		target := filepath.Join(dir, filepath.FromSlash(f.Name))
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, os.ModePerm); err != nil {
+				return err
+			}
+			continue
+		}
+		if !f.Mode().IsRegular() {
+			// Symlinks and others are skipped
+			continue
+		}
+		if err := extractFile(f, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractFile(f *zip.File, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		return err
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	// Entry sizes are checked in header, do not trust them while copying
+	_, err = io.Copy(out, io.LimitReader(rc, int64(f.UncompressedSize64)))
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// FindWorldDirs return folders containing level.dat in root,
+// looking into sub folders up to depth levels
+func FindWorldDirs(root string, depth int) ([]string, error) {
+	if _, err := os.Stat(filepath.Join(root, "level.dat")); err == nil {
+		return []string{root}, nil
+	}
+	if depth == 0 {
+		return nil, nil
+	}
+	infos, err := ioutil.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("mc.FindWorldDirs: %s", err)
+	}
+
+	ret := []string{}
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+		dirs, err := FindWorldDirs(filepath.Join(root, info.Name()), depth-1)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, dirs...)
+	}
+	return ret, nil
+}
@@ -0,0 +1,69 @@
+package mc
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func testArchive(t *testing.T, files map[string]string) *bytes.Reader {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestExtractArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r := testArchive(t, map[string]string{
+		"backup/world/level.dat":         "level",
+		"backup/world/region/r.0.0.mca":  "region",
+		"backup/world_nether/level.dat":  "nether",
+		"backup/world_nether/DIM-1/.tmp": "",
+	})
+	if err := ExtractArchive(r, r.Size(), dir); err != nil {
+		t.Fatal(err)
+	}
+	bs, err := ioutil.ReadFile(filepath.Join(dir, "backup", "world", "region", "r.0.0.mca"))
+	if err != nil || string(bs) != "region" {
+		t.Errorf("extracted file = %q, %v", bs, err)
+	}
+
+	got, err := FindWorldDirs(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "backup", "world"), filepath.Join(dir, "backup", "world_nether")}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("worlds mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, name := range []string{"../evil", "a/../../evil", "/abs/../../evil"} {
+		r := testArchive(t, map[string]string{name: "x"})
+		if err := ExtractArchive(r, r.Size(), filepath.Join(dir, "slip")); err == nil {
+			t.Errorf("entry %s should be rejected", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "evil")); !os.IsNotExist(err) {
+		t.Errorf("file escaped from target dir")
+	}
+}
@@ -0,0 +1,303 @@
+package mc
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path"
+)
+
+// ChunkPos is position of a chunk, in chunks
+type ChunkPos struct {
+	X int `json:"x"`
+	Z int `json:"z"`
+}
+
+// Box is a box of blocks, Min and Max are included
+type Box struct {
+	Min [3]int `json:"min"`
+	Max [3]int `json:"max"`
+}
+
+// BoxAround return the box within radius blocks of center
+func BoxAround(center [3]int, radius int) Box {
+	var box Box
+	for i := 0; i < 3; i++ {
+		box.Min[i] = center[i] - radius
+		box.Max[i] = center[i] + radius
+	}
+	return box
+}
+
+// Chunks return chunks overlapping the box
+func (b Box) Chunks() []ChunkPos {
+	ret := []ChunkPos{}
+	for cz := floorDiv(b.Min[2], 16); cz <= floorDiv(b.Max[2], 16); cz++ {
+		for cx := floorDiv(b.Min[0], 16); cx <= floorDiv(b.Max[0], 16); cx++ {
+			ret = append(ret, ChunkPos{cx, cz})
+		}
+	}
+	return ret
+}
+
+// dataVersionCavesCliffs is 1.18 where world height is extended
+const dataVersionCavesCliffs = 2825 // 21w37a
+
+// HeightRange return lowest and highest block Y of a dimension in a world,
+// the nether and the end are 0 ~ 255 in every version. Unknown data version
+// gives the widest range, which custom dimensions also use.
+func HeightRange(dim string, dataVersion int) (int, int) {
+	if dim == DimensionNether || dim == DimensionEnd {
+		return 0, 255
+	}
+	if dataVersion != 0 && dataVersion < dataVersionCavesCliffs {
+		return 0, 255
+	}
+	return -64, 319
+}
+
+// HasChunk check whether the chunk containing block x, z is generated
+func (w *World) HasChunk(dim string, x, z int) (bool, error) {
+	rr, err := newRegionReader(w.Path, dim)
+	if err != nil {
+		return false, fmt.Errorf("mc.World.HasChunk: %s", err)
+	}
+	defer rr.close()
+
+	pos := ChunkPos{floorDiv(x, 16), floorDiv(z, 16)}
+	r, err := rr.region(pos)
+	if err != nil {
+		return false, fmt.Errorf("mc.World.HasChunk: %s", err)
+	}
+	return r != nil && r.HasChunk(pos.X, pos.Z), nil
+}
+
+// WorldBorder is the farthest X and Z from origin a player can go
+const WorldBorder = 30000000
+
+// ValidatePosition check a position with pitch in degrees can be rendered,
+// it must be inside the world and its chunk is generated
+func (w *World) ValidatePosition(dim string, pos [3]float64, pitch float64) error {
+	if _, err := DimensionDir(w.Path, dim); err != nil {
+		return fmt.Errorf("mc.World.ValidatePosition: %s", err)
+	}
+	for _, v := range append(pos[:], pitch) {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("mc.World.ValidatePosition: bad number %v", v)
+		}
+	}
+	if math.Abs(pos[0]) > WorldBorder || math.Abs(pos[2]) > WorldBorder {
+		return fmt.Errorf("mc.World.ValidatePosition: X, Z (%v, %v) outside world border", pos[0], pos[2])
+	}
+	minY, maxY := HeightRange(dim, w.DataVersion)
+	if pos[1] < float64(minY) || pos[1] > float64(maxY)+1 {
+		return fmt.Errorf("mc.World.ValidatePosition: Y %v not in %d ~ %d", pos[1], minY, maxY)
+	}
+	if pitch < -90 || pitch > 90 {
+		return fmt.Errorf("mc.World.ValidatePosition: pitch %v not in -90 ~ 90", pitch)
+	}
+
+	x, z := int(math.Floor(pos[0])), int(math.Floor(pos[2]))
+	ok, err := w.HasChunk(dim, x, z)
+	if err != nil {
+		return fmt.Errorf("mc.World.ValidatePosition: %s", err)
+	}
+	if !ok {
+		return fmt.Errorf("mc.World.ValidatePosition: chunk (%d, %d) is not generated",
+			floorDiv(x, 16), floorDiv(z, 16))
+	}
+	return nil
+}
+
+// regionReader keeps regions opened while reading chunks of an area
+type regionReader struct {
+	dir     string
+	regions map[string]*Region
+}
+
+func newRegionReader(worldPath, dim string) (*regionReader, error) {
+	dimDir, err := DimensionDir(worldPath, dim)
+	if err != nil {
+		return nil, fmt.Errorf("mc.newRegionReader: %s", err)
+	}
+	return &regionReader{
+		dir:     path.Join(dimDir, "region"),
+		regions: map[string]*Region{},
+	}, nil
+}
+
+// region return region of chunk, nil if the region file not exists
+func (rr *regionReader) region(pos ChunkPos) (*Region, error) {
+	fn := RegionFilename(rr.dir, pos.X, pos.Z)
+	if r, ok := rr.regions[fn]; ok {
+		return r, nil
+	}
+	if _, err := os.Stat(fn); os.IsNotExist(err) {
+		rr.regions[fn] = nil
+		return nil, nil
+	}
+	r, err := OpenRegion(fn)
+	if err != nil {
+		return nil, fmt.Errorf("mc.regionReader.region: %s", err)
+	}
+	rr.regions[fn] = r
+	return r, nil
+}
+
+// chunk return nil if chunk is not generated
+func (rr *regionReader) chunk(pos ChunkPos) (*Chunk, error) {
+	r, err := rr.region(pos)
+	if err != nil || r == nil {
+		return nil, err
+	}
+	if !r.HasChunk(pos.X, pos.Z) {
+		return nil, nil
+	}
+	root, err := r.ReadChunk(pos.X, pos.Z)
+	if err != nil {
+		return nil, fmt.Errorf("mc.regionReader.chunk: %s", err)
+	}
+	return NewChunk(root)
+}
+
+func (rr *regionReader) close() {
+	for _, r := range rr.regions {
+		if r != nil {
+			r.Close()
+		}
+	}
+}
+
+// BlockCount is number of a block in a box, and the lowest and highest Y of it
+type BlockCount struct {
+	Count int64 `json:"count"`
+	MinY  int   `json:"min_y"`
+	MaxY  int   `json:"max_y"`
+}
+
+func (bc *BlockCount) add(count int64, minY, maxY int) {
+	if bc.Count == 0 || minY < bc.MinY {
+		bc.MinY = minY
+	}
+	if bc.Count == 0 || maxY > bc.MaxY {
+		bc.MaxY = maxY
+	}
+	bc.Count += count
+}
+
+// CountBlocks count non-air blocks in box by name, and return chunks overlapping
+// the box which are not fully generated. Blocks are counted by palette index
+// of sections, sections of a single block are counted without reading blocks.
+// Counting stops with the error of ctx when it is done.
+func (w *World) CountBlocks(ctx context.Context, dim string, box Box) (map[string]*BlockCount, []ChunkPos, error) {
+	rr, err := newRegionReader(w.Path, dim)
+	if err != nil {
+		return nil, nil, fmt.Errorf("mc.World.CountBlocks: %s", err)
+	}
+	defer rr.close()
+
+	counts := map[string]*BlockCount{}
+	add := func(name string, count int64, minY, maxY int) {
+		if count == 0 || IsAir(name) {
+			return
+		}
+		bc, ok := counts[name]
+		if !ok {
+			bc = &BlockCount{}
+			counts[name] = bc
+		}
+		bc.add(count, minY, maxY)
+	}
+
+	missing := []ChunkPos{}
+	for _, pos := range box.Chunks() {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, fmt.Errorf("mc.World.CountBlocks: %s", err)
+		}
+		chunk, err := rr.chunk(pos)
+		if err != nil {
+			return nil, nil, fmt.Errorf("mc.World.CountBlocks: %s", err)
+		}
+		if chunk == nil || !chunk.IsFull() {
+			missing = append(missing, pos)
+			continue
+		}
+		x0, z0 := pos.X*16, pos.Z*16
+		xMin, xMax := maxInt(box.Min[0], x0)-x0, minInt(box.Max[0], x0+15)-x0
+		zMin, zMax := maxInt(box.Min[2], z0)-z0, minInt(box.Max[2], z0+15)-z0
+		for _, section := range chunk.Sections {
+			y0 := section.Y * 16
+			yMin, yMax := maxInt(box.Min[1], y0)-y0, minInt(box.Max[1], y0+15)-y0
+			if yMin > yMax {
+				continue
+			}
+			if section.Blocks == nil {
+				area := int64((xMax - xMin + 1) * (zMax - zMin + 1))
+				add(section.Palette[0], area*int64(yMax-yMin+1), y0+yMin, y0+yMax)
+				continue
+			}
+			sc := make([]BlockCount, len(section.Palette))
+			for y := yMin; y <= yMax; y++ {
+				for z := zMin; z <= zMax; z++ {
+					row := section.Blocks[y<<8|z<<4 : y<<8|z<<4+16]
+					for x := xMin; x <= xMax; x++ {
+						sc[row[x]].add(1, y0+y, y0+y)
+					}
+				}
+			}
+			for i, bc := range sc {
+				add(section.Palette[i], bc.Count, bc.MinY, bc.MaxY)
+			}
+		}
+	}
+	return counts, missing, nil
+}
+
+// Coverage is which chunks around a render target are generated,
+// and estimated size of the scene
+type Coverage struct {
+	Dimension string     `json:"dimension"`
+	Box       Box        `json:"box"`
+	Total     int        `json:"total"` // Number of chunks overlapping box
+	Missing   []ChunkPos `json:"missing"`
+
+	Volume int64 `json:"volume"`  // Blocks in box
+	NonAir int64 `json:"non_air"` // Non-air blocks in box, fluids included, which become the scene
+}
+
+// ChunkCoverage check chunks within radius blocks of center,
+// chunks not fully generated are missing
+func (w *World) ChunkCoverage(ctx context.Context, dim string, center [3]int, radius int) (*Coverage, error) {
+	box := BoxAround(center, radius)
+	c := &Coverage{
+		Dimension: dim,
+		Box:       box,
+		Total:     len(box.Chunks()),
+	}
+	counts, missing, err := w.CountBlocks(ctx, dim, box)
+	if err != nil {
+		return nil, fmt.Errorf("mc.World.ChunkCoverage: %s", err)
+	}
+	c.Missing = missing
+	for _, bc := range counts {
+		c.NonAir += bc.Count
+	}
+	side := int64(2*radius + 1)
+	c.Volume = side * side * side
+	return c, nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,172 @@
+package mc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Editions of worlds
+const (
+	EditionJava    = "java"
+	EditionBedrock = "bedrock"
+)
+
+// bedrockHeaderSize is storage version and length before NBT of Bedrock level.dat
+const bedrockHeaderSize = 8
+
+// bedrockEyeHeight is height of eyes above feet, Bedrock keeps Pos of players at eyes
+const bedrockEyeHeight = 1.62
+
+// isBedrockLevelDat tells whether level.dat data is a Bedrock one,
+// which is uncompressed little-endian NBT after a header
+func isBedrockLevelDat(data []byte) bool {
+	return len(data) > bedrockHeaderSize &&
+		int(binary.LittleEndian.Uint32(data[4:])) == len(data)-bedrockHeaderSize &&
+		data[bedrockHeaderSize] == TagCompound
+}
+
+// DecodeBedrockLevelDat decode level.dat of Bedrock world
+func DecodeBedrockLevelDat(data []byte) (Compound, error) {
+	if !isBedrockLevelDat(data) {
+		return nil, fmt.Errorf("mc.DecodeBedrockLevelDat: not a Bedrock level.dat")
+	}
+	root, err := newNBTDecoder(bytes.NewReader(data[bedrockHeaderSize:]), binary.LittleEndian).decodeRoot()
+	if err != nil {
+		return nil, fmt.Errorf("mc.DecodeBedrockLevelDat: %s", err)
+	}
+	return root, nil
+}
+
+// newBedrockWorld read Bedrock world in dir with its level.dat data
+func newBedrockWorld(dir string, levelDatData []byte) (*World, error) {
+	levelDat, err := DecodeBedrockLevelDat(levelDatData)
+	if err != nil {
+		return nil, fmt.Errorf("mc.newBedrockWorld: %s", err)
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("mc.newBedrockWorld: %s", err)
+	}
+
+	world := &World{
+		Name:       filepath.Base(dir),
+		Folder:     filepath.Base(dir),
+		Path:       absDir,
+		Edition:    EditionBedrock,
+		Dimensions: []Dimension{{ID: DimensionOverworld}},
+		Players:    []*Player{},
+	}
+	world.readBedrockLevelData(levelDat)
+	if bs, err := ioutil.ReadFile(path.Join(dir, "levelname.txt")); err == nil {
+		if name := strings.TrimSpace(string(bs)); name != "" {
+			world.Name = name
+		}
+	}
+	world.Icon = findIcon(dir, "world_icon.jpeg")
+
+	// The database is complete if it has a manifest
+	dbDir := path.Join(dir, "db")
+	if _, err := os.Stat(path.Join(dbDir, "CURRENT")); err == nil {
+		world.Convertible = true
+		world.Players, err = listBedrockPlayers(dbDir)
+		if err != nil {
+			log.Println(err)
+			world.Players = []*Player{}
+		}
+		for _, p := range world.Players {
+			if p.Host {
+				p.LastSeen = world.LastPlayed
+			}
+		}
+	}
+	return world, nil
+}
+
+func (w *World) readBedrockLevelData(data Compound) {
+	if name := data.String("LevelName"); name != "" {
+		w.Name = name
+	}
+	// Like [1, 20, 10, 1, 0]
+	parts := []string{}
+	for _, v := range data.List("lastOpenedWithVersion") {
+		parts = append(parts, fmt.Sprint(nbtInt(v)))
+	}
+	for len(parts) > 3 && parts[len(parts)-1] == "0" {
+		parts = parts[:len(parts)-1]
+	}
+	w.Version = strings.Join(parts, ".")
+
+	w.Spawn = [3]int{
+		int(data.Int("SpawnX")),
+		int(data.Int("SpawnY")),
+		int(data.Int("SpawnZ")),
+	}
+	if gameType := data.Int("GameType"); gameType >= 0 && int(gameType) < len(gameModes) {
+		w.GameMode = gameModes[gameType]
+	}
+	w.Hardcore = data.Bool("IsHardcore")
+
+	// Seconds in Bedrock
+	w.LastPlayed = data.Int("LastPlayed") * 1000
+	w.DayTime = data.Int("Time")
+	w.Raining = data.Float("rainLevel") > 0
+	w.Thundering = data.Float("lightningLevel") > 0
+}
+
+// Keys of players in LevelDB, the local player is the host of singleplayer worlds
+const (
+	bedrockLocalPlayer  = "~local_player"
+	bedrockServerPrefix = "player_server_"
+)
+
+// bedrockDimensions are dimensions by DimensionId of players
+var bedrockDimensions = []string{DimensionOverworld, DimensionNether, DimensionEnd}
+
+// listBedrockPlayers read players in LevelDB of Bedrock world, host first.
+// Names of players are not stored, so they are listed by their keys.
+func listBedrockPlayers(dbDir string) ([]*Player, error) {
+	records, err := readLevelDB(dbDir, []string{bedrockLocalPlayer}, []string{bedrockServerPrefix})
+	if err != nil {
+		return nil, fmt.Errorf("mc.listBedrockPlayers: %s", err)
+	}
+
+	players := []*Player{}
+	var host *Player
+	for key, value := range records {
+		data, err := newNBTDecoder(bytes.NewReader(value), binary.LittleEndian).decodeRoot()
+		if err != nil {
+			log.Println("mc.listBedrockPlayers:", key, err)
+			continue
+		}
+		p := NewPlayer(strings.TrimPrefix(key, bedrockServerPrefix), data)
+		p.Name = p.UUID
+		p.Pos[1] -= bedrockEyeHeight
+		p.Dimension = DimensionOverworld
+		if dim := data.Int("DimensionId"); dim >= 0 && int(dim) < len(bedrockDimensions) {
+			p.Dimension = bedrockDimensions[dim]
+		}
+		if key == bedrockLocalPlayer {
+			p.UUID = ""
+			p.Name = hostName
+			p.Host = true
+			host = p
+			continue
+		}
+		players = append(players, p)
+	}
+	sort.Slice(players, func(i, j int) bool {
+		return players[i].Name < players[j].Name
+	})
+	if host != nil {
+		players = append([]*Player{host}, players...)
+	}
+	return players, nil
+}
@@ -0,0 +1,309 @@
+package mc
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func testBedrockLevelDat() []byte {
+	w := &nbtWriter{order: binary.LittleEndian}
+	w.name(TagCompound, "")
+	w.name(TagString, "LevelName").value("Bedrock Level")
+	w.name(TagList, "lastOpenedWithVersion").value(TagInt).value(int32(5)).
+		value([]int32{1, 20, 10, 1, 0})
+	w.name(TagInt, "SpawnX").value(int32(8))
+	w.name(TagInt, "SpawnY").value(int32(70))
+	w.name(TagInt, "SpawnZ").value(int32(-8))
+	w.name(TagInt, "GameType").value(int32(0))
+	w.name(TagLong, "LastPlayed").value(int64(1700000000))
+	w.name(TagLong, "Time").value(int64(13000))
+	w.name(TagFloat, "rainLevel").value(float32(1))
+	w.name(TagFloat, "lightningLevel").value(float32(0))
+	w.end()
+
+	var header [bedrockHeaderSize]byte
+	binary.LittleEndian.PutUint32(header[:], 10)
+	binary.LittleEndian.PutUint32(header[4:], uint32(w.Len()))
+	return append(header[:], w.Bytes()...)
+}
+
+func testBedrockPlayer(x, y, z float32, dim int32) []byte {
+	w := &nbtWriter{order: binary.LittleEndian}
+	w.name(TagCompound, "")
+	w.name(TagList, "Pos").value(TagFloat).value(int32(3)).value([]float32{x, y, z})
+	w.name(TagList, "Rotation").value(TagFloat).value(int32(2)).value([]float32{90, -10})
+	w.name(TagInt, "DimensionId").value(dim)
+	w.end()
+	return w.Bytes()
+}
+
+// levelDBRecord is a record of a write batch, deletion if value is nil
+type levelDBRecord struct {
+	key, value []byte
+}
+
+func putUvarint(buf *bytes.Buffer, n int) {
+	var bs [binary.MaxVarintLen64]byte
+	buf.Write(bs[:binary.PutUvarint(bs[:], uint64(n))])
+}
+
+func testLevelDBBatch(seq uint64, records ...levelDBRecord) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, seq)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(records)))
+	for _, r := range records {
+		if r.value == nil {
+			buf.WriteByte(0)
+		} else {
+			buf.WriteByte(levelDBValueKind)
+		}
+		putUvarint(&buf, len(r.key))
+		buf.Write(r.key)
+		if r.value != nil {
+			putUvarint(&buf, len(r.value))
+			buf.Write(r.value)
+		}
+	}
+	return buf.Bytes()
+}
+
+// testLevelDBLog write batches as log records, the last one is split into two
+func testLevelDBLog(batches ...[]byte) []byte {
+	var buf bytes.Buffer
+	record := func(kind byte, data []byte) {
+		buf.Write(make([]byte, 4)) // Checksum
+		binary.Write(&buf, binary.LittleEndian, uint16(len(data)))
+		buf.WriteByte(kind)
+		buf.Write(data)
+	}
+	for i, batch := range batches {
+		if i < len(batches)-1 {
+			record(levelDBLogFull, batch)
+			continue
+		}
+		record(levelDBLogFirst, batch[:10])
+		record(levelDBLogLast, batch[10:])
+	}
+	return buf.Bytes()
+}
+
+func testLevelDBBlock(entries ...levelDBRecord) []byte {
+	var buf bytes.Buffer
+	for _, e := range entries {
+		putUvarint(&buf, 0)
+		putUvarint(&buf, len(e.key))
+		putUvarint(&buf, len(e.value))
+		buf.Write(e.key)
+		buf.Write(e.value)
+	}
+	binary.Write(&buf, binary.LittleEndian, uint32(0)) // Restart
+	binary.Write(&buf, binary.LittleEndian, uint32(1))
+	return buf.Bytes()
+}
+
+// testLevelDBTable write a table of one data block compressed by raw deflate,
+// records have sequence seq
+func testLevelDBTable(t *testing.T, seq uint64, records ...levelDBRecord) []byte {
+	entries := []levelDBRecord{}
+	for _, r := range records {
+		var trailer [8]byte
+		binary.LittleEndian.PutUint64(trailer[:], seq<<8|levelDBValueKind)
+		entries = append(entries, levelDBRecord{append(r.key, trailer[:]...), r.value})
+	}
+	data := compress(t, testLevelDBBlock(entries...), func(w io.Writer) io.WriteCloser {
+		fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+		return fw
+	})
+
+	var buf bytes.Buffer
+	buf.Write(data)
+	buf.Write([]byte{levelDBRawDeflate, 0, 0, 0, 0})
+	var handle bytes.Buffer
+	putUvarint(&handle, 0)
+	putUvarint(&handle, len(data))
+	// Separator is the last key of the block
+	index := testLevelDBBlock(levelDBRecord{entries[len(entries)-1].key, handle.Bytes()})
+	indexOffset := buf.Len()
+	buf.Write(index)
+	buf.Write([]byte{levelDBNoCompression, 0, 0, 0, 0})
+
+	var footer bytes.Buffer
+	putUvarint(&footer, 0) // Metaindex
+	putUvarint(&footer, 0)
+	putUvarint(&footer, indexOffset)
+	putUvarint(&footer, len(index))
+	footer.Write(make([]byte, levelDBFooterSize-8-footer.Len()))
+	binary.Write(&footer, binary.LittleEndian, uint64(levelDBTableMagic))
+	buf.Write(footer.Bytes())
+	return buf.Bytes()
+}
+
+func TestNewBedrockWorld(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bedrock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	world := path.Join(dir, "AbCdEf=")
+	db := path.Join(world, "db")
+	if err := os.MkdirAll(db, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		"level.dat":       testBedrockLevelDat(),
+		"levelname.txt":   []byte("My Bedrock World\n"),
+		"world_icon.jpeg": []byte("jpeg"),
+		"db/CURRENT":      []byte("MANIFEST-000001\n"),
+		"db/000005.ldb": testLevelDBTable(t, 1,
+			levelDBRecord{[]byte("player_server_gone"), testBedrockPlayer(0, 0, 0, 0)},
+			levelDBRecord{[]byte("player_server_old"), testBedrockPlayer(1, 71.62, 2, 1)},
+		),
+		"db/000006.log": testLevelDBLog(
+			testLevelDBBatch(10,
+				levelDBRecord{[]byte("~local_player"), testBedrockPlayer(0, 80, 0, 0)},
+				levelDBRecord{[]byte("player_server_gone"), nil},
+				levelDBRecord{[]byte("chunk"), []byte{1}},
+			),
+			testLevelDBBatch(20,
+				levelDBRecord{[]byte("~local_player"), testBedrockPlayer(5.5, 65.62, -3.5, 2)},
+			),
+		),
+	}
+	for fn, data := range files {
+		if err := ioutil.WriteFile(path.Join(world, fn), data, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := NewWorld(world)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &World{
+		Name:        "My Bedrock World",
+		Folder:      "AbCdEf=",
+		Path:        world,
+		Icon:        "world_icon.jpeg",
+		Edition:     EditionBedrock,
+		Convertible: true,
+		Dimensions:  []Dimension{{ID: DimensionOverworld}},
+		Version:     "1.20.10.1",
+		Spawn:       [3]int{8, 70, -8},
+		GameMode:    "survival",
+		LastPlayed:  1700000000000,
+		DayTime:     13000,
+		Raining:     true,
+		Players: []*Player{
+			{
+				Name:      hostName,
+				Pos:       [3]float64{5.5, 64, -3.5},
+				Rotation:  [2]float32{90, -10},
+				Dimension: DimensionEnd,
+				LastSeen:  1700000000000,
+				Host:      true,
+			},
+			{
+				UUID:      "old",
+				Name:      "old",
+				Pos:       [3]float64{1, 70, 2},
+				Rotation:  [2]float32{90, -10},
+				Dimension: DimensionNether,
+			},
+		},
+	}
+	approx := cmp.Comparer(func(a, b float64) bool {
+		return a-b < 1e-4 && b-a < 1e-4
+	})
+	if diff := cmp.Diff(want, got, approx); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLevelDBBlockBounds(t *testing.T) {
+	bs := append([]byte("data"), levelDBNoCompression, 0, 0, 0, 0)
+	if block, err := levelDBBlock(bytes.NewReader(bs), uint64(len(bs)), 0, 4); err != nil || string(block) != "data" {
+		t.Errorf("block = %q, %v", block, err)
+	}
+	for _, h := range [][2]uint64{
+		{0, 5},
+		{5, 0},
+		{10, 0},
+		{^uint64(0), 2},
+		{1, ^uint64(0)},
+		{^uint64(0) - 3, 4},
+	} {
+		if _, err := levelDBBlock(bytes.NewReader(bs), uint64(len(bs)), h[0], h[1]); err == nil {
+			t.Errorf("block at %d size %d should be out of file", h[0], h[1])
+		}
+	}
+
+	// Table with index handle near the end of uint64
+	table := testLevelDBTable(t, 1, levelDBRecord{[]byte("k"), []byte("v")})
+	var footer bytes.Buffer
+	putUvarint(&footer, 0)
+	putUvarint(&footer, 0)
+	binary.Write(&footer, binary.LittleEndian, [10]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01})
+	putUvarint(&footer, 2)
+	footer.Write(make([]byte, levelDBFooterSize-8-footer.Len()))
+	binary.Write(&footer, binary.LittleEndian, uint64(levelDBTableMagic))
+	copy(table[len(table)-levelDBFooterSize:], footer.Bytes())
+
+	dir, err := ioutil.TempDir("", "leveldb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(path.Join(dir, "000005.ldb"), table, 0666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readLevelDB(dir, []string{"k"}, nil); err == nil {
+		t.Error("table with index out of file should fail")
+	}
+}
+
+func TestReadLevelDBSkipBlocks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "leveldb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Blocks of chunks are broken, they are not read for players
+	chunks := testLevelDBTable(t, 1, levelDBRecord{[]byte("chunk"), []byte{1}})
+	// The data block is followed by its compression type and the index block
+	footer := bytes.NewReader(chunks[len(chunks)-levelDBFooterSize:])
+	var handles [3]uint64
+	for i := range handles {
+		handles[i], _ = binary.ReadUvarint(footer)
+	}
+	chunks[handles[2]-5] = 9
+	players := testLevelDBTable(t, 2,
+		levelDBRecord{[]byte("player_server_a"), []byte("a")},
+		levelDBRecord{[]byte("zzz"), []byte("z")},
+	)
+	for fn, data := range map[string][]byte{"000005.ldb": chunks, "000006.ldb": players} {
+		if err := ioutil.WriteFile(path.Join(dir, fn), data, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := readLevelDB(dir, []string{bedrockLocalPlayer}, []string{bedrockServerPrefix})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(map[string][]byte{"player_server_a": []byte("a")}, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := readLevelDB(dir, []string{"chunk"}, nil); err == nil {
+		t.Error("broken block of chunk should be read")
+	}
+}
@@ -0,0 +1,242 @@
+package mc
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Data versions where chunk format changed
+const (
+	dataVersionFlattening  = 1451 // 17w47a, 1.13 block names and palettes
+	dataVersionPackedLongs = 2527 // 20w17a, 1.16 block states do not span longs
+	dataVersionNoLevel     = 2844 // 21w43a, 1.18 no Level compound
+)
+
+// BlockAir is the name of air block
+const BlockAir = "minecraft:air"
+
+// Chunk stores blocks of a 16 blocks wide column
+type Chunk struct {
+	X, Z        int
+	DataVersion int
+	Status      string     // Generation status, "" before 1.13
+	Sections    []*Section // Sorted by Y
+}
+
+// Section is 16x16x16 blocks of a chunk
+type Section struct {
+	Y       int      // Y of section, block Y is Y*16 ~ Y*16+15
+	Palette []string // Block names
+	Blocks  []uint16 // Palette index in YZX order, nil if only one block in palette
+}
+
+// NewChunk read blocks in chunk NBT from region file
+func NewChunk(root Compound) (*Chunk, error) {
+	c := &Chunk{DataVersion: int(root.Int("DataVersion"))}
+	level := root
+	if c.DataVersion < dataVersionNoLevel {
+		level = root.Compound("Level")
+		if level == nil {
+			return nil, fmt.Errorf("mc.NewChunk: Level not found")
+		}
+	}
+	c.X = int(level.Int("xPos"))
+	c.Z = int(level.Int("zPos"))
+	c.Status = level.String("Status")
+
+	sections := level.List("sections")
+	if sections == nil {
+		sections = level.List("Sections")
+	}
+	for _, v := range sections {
+		data, ok := v.(Compound)
+		if !ok {
+			continue
+		}
+		var section *Section
+		var err error
+		switch {
+		case c.DataVersion >= dataVersionNoLevel:
+			states := data.Compound("block_states")
+			if states == nil {
+				continue
+			}
+			section, err = newPaletteSection(states.List("palette"), states["data"], false)
+		case c.DataVersion >= dataVersionFlattening:
+			if !data.Has("Palette") {
+				continue
+			}
+			section, err = newPaletteSection(data.List("Palette"), data["BlockStates"],
+				c.DataVersion < dataVersionPackedLongs)
+		default:
+			if !data.Has("Blocks") {
+				continue
+			}
+			section = newLegacySection(data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("mc.NewChunk: chunk (%d, %d): %s", c.X, c.Z, err)
+		}
+		section.Y = int(data.Int("Y"))
+		c.Sections = append(c.Sections, section)
+	}
+	sort.Slice(c.Sections, func(i, j int) bool {
+		return c.Sections[i].Y < c.Sections[j].Y
+	})
+	return c, nil
+}
+
+func newPaletteSection(palette []interface{}, states interface{}, spanning bool) (*Section, error) {
+	section := &Section{}
+	for _, v := range palette {
+		entry, _ := v.(Compound)
+		section.Palette = append(section.Palette, entry.String("Name"))
+	}
+	if len(section.Palette) == 0 {
+		return nil, fmt.Errorf("empty palette")
+	}
+	if len(section.Palette) == 1 {
+		return section, nil
+	}
+
+	longs, _ := states.([]int64)
+	bits := 4
+	for 1<<uint(bits) < len(section.Palette) {
+		bits++
+	}
+	blocks, err := unpackLongs(longs, bits, 4096, spanning)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range blocks {
+		if int(b) >= len(section.Palette) {
+			return nil, fmt.Errorf("palette index %d out of range", b)
+		}
+	}
+	section.Blocks = blocks
+	return section, nil
+}
+
+// unpackLongs unpack n values of bits each,
+// spanning values may be split between two longs, before 1.16
+func unpackLongs(longs []int64, bits, n int, spanning bool) ([]uint16, error) {
+	perLong := 64 / bits
+	need := (n + perLong - 1) / perLong
+	if spanning {
+		need = (n*bits + 63) / 64
+	}
+	if len(longs) < need {
+		return nil, fmt.Errorf("%d longs for %d bits values, need %d", len(longs), bits, need)
+	}
+
+	mask := uint64(1)<<uint(bits) - 1
+	ret := make([]uint16, n)
+	for i := 0; i < n; i++ {
+		if spanning {
+			bitIdx := i * bits
+			idx, off := bitIdx/64, uint(bitIdx%64)
+			v := uint64(longs[idx]) >> off
+			if int(off)+bits > 64 {
+				v |= uint64(longs[idx+1]) << (64 - off)
+			}
+			ret[i] = uint16(v & mask)
+		} else {
+			v := uint64(longs[i/perLong]) >> uint((i%perLong)*bits)
+			ret[i] = uint16(v & mask)
+		}
+	}
+	return ret, nil
+}
+
+// newLegacySection read numeric block IDs used before 1.13
+func newLegacySection(data Compound) *Section {
+	ids, _ := data["Blocks"].([]byte)
+	add, _ := data["Add"].([]byte)
+	section := &Section{Blocks: make([]uint16, 4096)}
+	paletteIndex := map[int]uint16{}
+	for i := 0; i < 4096 && i < len(ids); i++ {
+		id := int(ids[i])
+		if len(add) == 2048 {
+			id |= int(add[i/2]>>(uint(i%2)*4)&0x0f) << 8
+		}
+		idx, ok := paletteIndex[id]
+		if !ok {
+			idx = uint16(len(section.Palette))
+			paletteIndex[id] = idx
+			section.Palette = append(section.Palette, legacyBlockName(id))
+		}
+		section.Blocks[i] = idx
+	}
+	if len(section.Palette) == 0 {
+		section.Palette = []string{BlockAir}
+		section.Blocks = nil
+	}
+	return section
+}
+
+// IsFull check whether the chunk is fully generated, proto-chunks still being
+// generated have missing terrain. Chunks before 1.13 have no status and are full.
+func (c *Chunk) IsFull() bool {
+	switch c.Status {
+	case "", "full", "minecraft:full", "fullchunk", "postprocessed":
+		return true
+	}
+	return false
+}
+
+// Block return name of block at (x, y, z) in section coordinate
+func (s *Section) Block(x, y, z int) string {
+	if s.Blocks == nil {
+		return s.Palette[0]
+	}
+	return s.Palette[s.Blocks[y<<8|z<<4|x]]
+}
+
+// Block return name of block at local x, z and world y,
+// air if the section is not stored
+func (c *Chunk) Block(x, y, z int) string {
+	sy := floorDiv(y, 16)
+	idx := sort.Search(len(c.Sections), func(i int) bool {
+		return c.Sections[i].Y >= sy
+	})
+	if idx == len(c.Sections) || c.Sections[idx].Y != sy {
+		return BlockAir
+	}
+	return c.Sections[idx].Block(x, floorMod(y, 16), z)
+}
+
+// IsAir check whether a block is any kind of air
+func IsAir(name string) bool {
+	switch name {
+	case BlockAir, "minecraft:cave_air", "minecraft:void_air", "":
+		return true
+	}
+	return false
+}
+
+// legacyBlockNames maps common numeric IDs before 1.13 to names
+var legacyBlockNames = map[int]string{
+	0: "air", 1: "stone", 2: "grass_block", 3: "dirt", 4: "cobblestone",
+	5: "oak_planks", 6: "oak_sapling", 7: "bedrock", 8: "water", 9: "water",
+	10: "lava", 11: "lava", 12: "sand", 13: "gravel", 14: "gold_ore",
+	15: "iron_ore", 16: "coal_ore", 17: "oak_log", 18: "oak_leaves", 19: "sponge",
+	20: "glass", 21: "lapis_ore", 22: "lapis_block", 24: "sandstone", 31: "grass",
+	35: "white_wool", 37: "dandelion", 38: "poppy", 41: "gold_block", 42: "iron_block",
+	45: "bricks", 46: "tnt", 47: "bookshelf", 48: "mossy_cobblestone", 49: "obsidian",
+	50: "torch", 51: "fire", 53: "oak_stairs", 54: "chest", 56: "diamond_ore",
+	57: "diamond_block", 58: "crafting_table", 60: "farmland", 61: "furnace", 62: "furnace",
+	78: "snow", 79: "ice", 80: "snow_block", 81: "cactus", 82: "clay",
+	83: "sugar_cane", 86: "pumpkin", 87: "netherrack", 88: "soul_sand", 89: "glowstone",
+	91: "jack_o_lantern", 95: "white_stained_glass", 98: "stone_bricks", 102: "glass_pane", 103: "melon",
+	106: "vine", 110: "mycelium", 111: "lily_pad", 112: "nether_bricks", 121: "end_stone",
+	124: "redstone_lamp", 138: "beacon", 155: "quartz_block", 159: "white_terracotta", 160: "white_stained_glass_pane",
+	161: "acacia_leaves", 162: "acacia_log", 169: "sea_lantern", 172: "terracotta", 174: "packed_ice",
+	179: "red_sandstone", 198: "end_rod", 208: "grass_path",
+}
+
+func legacyBlockName(id int) string {
+	if name, ok := legacyBlockNames[id]; ok {
+		return "minecraft:" + name
+	}
+	return fmt.Sprintf("minecraft:legacy_%d", id)
+}
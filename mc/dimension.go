@@ -0,0 +1,118 @@
+package mc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Dimension is a dimension found in world folder
+type Dimension struct {
+	ID     string `json:"id"`     // Like minecraft:the_nether
+	Folder string `json:"folder"` // Relative to world folder, "" for overworld
+}
+
+// dimensionIDRegexp matches namespaced IDs of custom dimensions
+var dimensionIDRegexp = regexp.MustCompile(`^[a-z0-9_.-]+:[a-z0-9_./-]+$`)
+
+// dimensionFolder return folder of dimension relative to world folder.
+// Vanilla dimensions are in DIM-1 and DIM1, others in dimensions/<namespace>/<path> since 1.16.
+func dimensionFolder(dim string) (string, error) {
+	switch dim {
+	case "", DimensionOverworld:
+		return "", nil
+	case DimensionNether:
+		return "DIM-1", nil
+	case DimensionEnd:
+		return "DIM1", nil
+	}
+	if !dimensionIDRegexp.MatchString(dim) {
+		return "", fmt.Errorf("mc.dimensionFolder: unknown dimension %s", dim)
+	}
+	parts := strings.SplitN(dim, ":", 2)
+	for _, p := range strings.Split(parts[1], "/") {
+		if p == "" || p == "." || p == ".." {
+			return "", fmt.Errorf("mc.dimensionFolder: bad dimension %s", dim)
+		}
+	}
+	return path.Join("dimensions", parts[0], parts[1]), nil
+}
+
+// DimensionDir return folder of a dimension in world folder
+func DimensionDir(worldPath, dim string) (string, error) {
+	folder, err := dimensionFolder(dim)
+	if err != nil {
+		return "", fmt.Errorf("mc.DimensionDir: %s", err)
+	}
+	return path.Join(worldPath, folder), nil
+}
+
+// listDimensions find dimensions having region folder in world folder,
+// overworld is always listed first
+func listDimensions(worldPath string) ([]Dimension, error) {
+	dims := []Dimension{{ID: DimensionOverworld}}
+	for _, dim := range []string{DimensionNether, DimensionEnd} {
+		folder, _ := dimensionFolder(dim)
+		if isDir(path.Join(worldPath, folder, "region")) {
+			dims = append(dims, Dimension{ID: dim, Folder: folder})
+		}
+	}
+
+	// dimensions/<namespace>/<path...>/region
+	root := path.Join(worldPath, "dimensions")
+	namespaces, err := ioutil.ReadDir(root)
+	if os.IsNotExist(err) {
+		return dims, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("mc.listDimensions: %s", err)
+	}
+	for _, ns := range namespaces {
+		if !ns.IsDir() {
+			continue
+		}
+		var walk func(rel string) error
+		walk = func(rel string) error {
+			dir := path.Join(root, ns.Name(), rel)
+			if rel != "" && isDir(path.Join(dir, "region")) {
+				id := ns.Name() + ":" + rel
+				if _, err := dimensionFolder(id); err == nil && !hasDimension(dims, id) {
+					dims = append(dims, Dimension{ID: id, Folder: path.Join("dimensions", ns.Name(), rel)})
+				}
+				return nil
+			}
+			infos, err := ioutil.ReadDir(dir)
+			if err != nil {
+				return err
+			}
+			for _, info := range infos {
+				if info.IsDir() {
+					if err := walk(path.Join(rel, info.Name())); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}
+		if err := walk(""); err != nil {
+			return nil, fmt.Errorf("mc.listDimensions: %s", err)
+		}
+	}
+	return dims, nil
+}
+
+func hasDimension(dims []Dimension, id string) bool {
+	for _, dim := range dims {
+		if dim.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func isDir(name string) bool {
+	info, err := os.Stat(name)
+	return err == nil && info.IsDir()
+}
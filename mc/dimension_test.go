@@ -0,0 +1,58 @@
+package mc
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestListDimensions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dimension")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, folder := range []string{
+		"region", "DIM1/region", "DIM-1/data",
+		"dimensions/minecraft/overworld/region",
+		"dimensions/mymod/deep/cave/region",
+		"dimensions/mymod/empty",
+	} {
+		if err := os.MkdirAll(path.Join(dir, folder), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := listDimensions(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Dimension{
+		{ID: DimensionOverworld},
+		{ID: DimensionEnd, Folder: "DIM1"},
+		{ID: "mymod:deep/cave", Folder: "dimensions/mymod/deep/cave"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDimensionDir(t *testing.T) {
+	for dim, want := range map[string]string{
+		"":                   "w",
+		DimensionOverworld:   "w",
+		DimensionNether:      "w/DIM-1",
+		"mymod:deep/cave":    "w/dimensions/mymod/deep/cave",
+		"mymod:../../escape": "",
+		"Bad:Name":           "",
+	} {
+		got, err := DimensionDir("w", dim)
+		if (err != nil) != (want == "") || got != want {
+			t.Errorf("DimensionDir(%s) = %s, %v, want %s", dim, got, err, want)
+		}
+	}
+}
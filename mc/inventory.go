@@ -0,0 +1,126 @@
+package mc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Inventory is what blocks are in a box, to predict render cost.
+// Light emitters add direct lighting, and water, lava and glass
+// need more bounces and samples. Water and lava are counted in NonAir.
+type Inventory struct {
+	Dimension string     `json:"dimension"`
+	Box       Box        `json:"box"`
+	Missing   []ChunkPos `json:"missing"`
+
+	Blocks map[string]int64 `json:"blocks"`  // Non-air blocks by name
+	NonAir int64            `json:"non_air"` // Non-air blocks, fluids included
+	Lights map[string]int64 `json:"lights"`  // Light emitting blocks by name
+	Water  int64            `json:"water"`   // Water and blocks always in water, like kelp
+	Lava   int64            `json:"lava"`
+	Glass  int64            `json:"glass"` // Glass blocks and panes
+
+	// MinY and MaxY are the lowest and highest non-air blocks, 0 if NonAir is 0
+	MinY int `json:"min_y"`
+	MaxY int `json:"max_y"`
+}
+
+// lightLevels is light level of blocks which always emit light,
+// ones depending on block states like redstone lamps and candles are not listed
+var lightLevels = map[string]int{
+	"beacon":                15,
+	"campfire":              15,
+	"conduit":               15,
+	"end_gateway":           15,
+	"end_portal":            15,
+	"fire":                  15,
+	"glowstone":             15,
+	"jack_o_lantern":        15,
+	"lantern":               15,
+	"lava":                  15,
+	"ochre_froglight":       15,
+	"pearlescent_froglight": 15,
+	"verdant_froglight":     15,
+	"sea_lantern":           15,
+	"shroomlight":           15,
+	"end_rod":               14,
+	"torch":                 14,
+	"wall_torch":            14,
+	"nether_portal":         11,
+	"crying_obsidian":       10,
+	"soul_campfire":         10,
+	"soul_fire":             10,
+	"soul_lantern":          10,
+	"soul_torch":            10,
+	"soul_wall_torch":       10,
+	"enchanting_table":      7,
+	"ender_chest":           7,
+	"glow_lichen":           7,
+	"redstone_torch":        7,
+	"redstone_wall_torch":   7,
+	"sculk_catalyst":        6,
+	"amethyst_cluster":      5,
+	"large_amethyst_bud":    4,
+	"magma_block":           3,
+	"medium_amethyst_bud":   2,
+	"brewing_stand":         1,
+	"brown_mushroom":        1,
+	"dragon_egg":            1,
+	"end_portal_frame":      1,
+	"small_amethyst_bud":    1,
+}
+
+// LightLevel return light level emitted by block, 0 if it emits no light
+func LightLevel(name string) int {
+	return lightLevels[strings.TrimPrefix(name, "minecraft:")]
+}
+
+// waterBlocks are blocks which are always in water
+var waterBlocks = map[string]bool{
+	"water":         true,
+	"kelp":          true,
+	"kelp_plant":    true,
+	"seagrass":      true,
+	"tall_seagrass": true,
+	"bubble_column": true,
+}
+
+// BlockInventory count blocks within radius blocks of center,
+// it stops with the error of ctx when it is done
+func (w *World) BlockInventory(ctx context.Context, dim string, center [3]int, radius int) (*Inventory, error) {
+	box := BoxAround(center, radius)
+	counts, missing, err := w.CountBlocks(ctx, dim, box)
+	if err != nil {
+		return nil, fmt.Errorf("mc.World.BlockInventory: %s", err)
+	}
+	inv := &Inventory{
+		Dimension: dim,
+		Box:       box,
+		Missing:   missing,
+		Blocks:    map[string]int64{},
+		Lights:    map[string]int64{},
+	}
+	for name, bc := range counts {
+		if inv.NonAir == 0 || bc.MinY < inv.MinY {
+			inv.MinY = bc.MinY
+		}
+		if inv.NonAir == 0 || bc.MaxY > inv.MaxY {
+			inv.MaxY = bc.MaxY
+		}
+		inv.NonAir += bc.Count
+		inv.Blocks[name] += bc.Count
+		if LightLevel(name) > 0 {
+			inv.Lights[name] += bc.Count
+		}
+		switch short := strings.TrimPrefix(name, "minecraft:"); {
+		case waterBlocks[short]:
+			inv.Water += bc.Count
+		case short == "lava":
+			inv.Lava += bc.Count
+		case strings.Contains(short, "glass"):
+			inv.Glass += bc.Count
+		}
+	}
+	return inv, nil
+}
@@ -0,0 +1,96 @@
+package mc
+
+import (
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// testLayerChunk builds a 1.18 chunk whose layer y is filled with layers[y]
+func testLayerChunk(cx, cz int, layers ...string) []byte {
+	longs := make([]int64, 256)
+	for i := 0; i < len(layers)*256; i++ {
+		// 4 bits per block, 16 blocks per long, palette index 0 is air
+		longs[i/16] |= int64(i/256+1) << uint((i%16)*4)
+	}
+
+	w := &nbtWriter{order: binary.BigEndian}
+	w.name(TagCompound, "")
+	w.name(TagInt, "DataVersion").value(int32(2975))
+	w.name(TagInt, "xPos").value(int32(cx))
+	w.name(TagInt, "zPos").value(int32(cz))
+	w.name(TagList, "sections").value(TagCompound).value(int32(1))
+	w.name(TagByte, "Y").value(int8(0))
+	w.name(TagCompound, "block_states")
+	w.name(TagList, "palette").value(TagCompound).value(int32(len(layers) + 1))
+	w.name(TagString, "Name").value(BlockAir).end()
+	for _, name := range layers {
+		w.name(TagString, "Name").value(name).end()
+	}
+	w.name(TagLongArray, "data").value(int32(len(longs))).value(longs)
+	w.end() // block_states
+	w.end() // section
+	w.end()
+	return w.Bytes()
+}
+
+func TestBlockInventory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inventory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeRegion(t, path.Join(dir, "region"), 0, 0, map[ChunkPos][]byte{
+		{0, 0}: testLayerChunk(0, 0,
+			"minecraft:stone", "minecraft:water", "minecraft:lava",
+			"minecraft:white_stained_glass", "minecraft:torch", "minecraft:kelp_plant"),
+	})
+
+	w := &World{Path: dir}
+	// x in 0..2, y in 1..5, z in 0..2, x and z below 0 are in missing chunks
+	got, err := w.BlockInventory(context.Background(), DimensionOverworld, [3]int{0, 3, 0}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Inventory{
+		Dimension: DimensionOverworld,
+		Box:       Box{Min: [3]int{-2, 1, -2}, Max: [3]int{2, 5, 2}},
+		Missing:   []ChunkPos{{-1, -1}, {0, -1}, {-1, 0}},
+		Blocks: map[string]int64{
+			"minecraft:water":               9,
+			"minecraft:lava":                9,
+			"minecraft:white_stained_glass": 9,
+			"minecraft:torch":               9,
+			"minecraft:kelp_plant":          9,
+		},
+		NonAir: 45,
+		Lights: map[string]int64{"minecraft:lava": 9, "minecraft:torch": 9},
+		Water:  18,
+		Lava:   9,
+		Glass:  9,
+		MinY:   1,
+		MaxY:   5,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLightLevel(t *testing.T) {
+	for name, want := range map[string]int{
+		"minecraft:torch":         14,
+		"minecraft:soul_lantern":  10,
+		"minecraft:stone":         0,
+		"minecraft:redstone_lamp": 0,
+	} {
+		if got := LightLevel(name); got != want {
+			t.Errorf("LightLevel(%s) = %d, want %d", name, got, want)
+		}
+	}
+}
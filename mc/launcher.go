@@ -0,0 +1,199 @@
+package mc
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Installation is a game folder of a launcher or instance, or a server folder
+type Installation struct {
+	Name     string `json:"name"`     // Instance name, launcher or folder name
+	Launcher string `json:"launcher"` // Like "Prism Launcher (Flatpak)"
+	Dir      string `json:"dir"`      // Game folder with resourcepacks and versions
+	Saves    string `json:"saves"`    // Folder of worlds, may not exist yet
+}
+
+// Label return launcher and name of installation, like "Prism Launcher: Fabric 1.20"
+func (inst *Installation) Label() string {
+	if inst.Launcher == "" || inst.Launcher == inst.Name {
+		return inst.Name
+	}
+	return inst.Launcher + ": " + inst.Name
+}
+
+// LauncherRoot is a folder to find game folders in, which is a game folder,
+// a launcher folder with instances, a folder of instances or a server folder
+type LauncherRoot struct {
+	Launcher string
+	Dir      string
+}
+
+// launcherServer names installations of server folders
+const launcherServer = "Server"
+
+// vanillaRoots return folders of the official launcher
+func vanillaRoots(goos, home, appData string) []LauncherRoot {
+	switch goos {
+	case "windows":
+		return []LauncherRoot{{"Minecraft Launcher", path.Join(appData, ".minecraft")}}
+	case "darwin":
+		return []LauncherRoot{{"Minecraft Launcher", path.Join(home, "Library", "Application Support", "minecraft")}}
+	case "linux":
+		return []LauncherRoot{
+			{"Minecraft Launcher", path.Join(home, ".minecraft")},
+			{"Minecraft Launcher (Flatpak)", path.Join(home, ".var", "app", "com.mojang.Minecraft", ".minecraft")},
+			{"Minecraft Launcher (snap)", path.Join(home, "snap", "mc-installer", "current", ".minecraft")},
+		}
+	}
+	return nil
+}
+
+// defaultLauncherRoots return folders of the official launcher, Prism Launcher,
+// PolyMC and MultiMC, including their Flatpak and snap installs
+func defaultLauncherRoots(goos, home, appData string) []LauncherRoot {
+	roots := vanillaRoots(goos, home, appData)
+	switch goos {
+	case "windows":
+		roots = append(roots,
+			LauncherRoot{"Prism Launcher", path.Join(appData, "PrismLauncher")},
+			LauncherRoot{"PolyMC", path.Join(appData, "PolyMC")},
+		)
+	case "darwin":
+		support := path.Join(home, "Library", "Application Support")
+		roots = append(roots,
+			LauncherRoot{"Prism Launcher", path.Join(support, "PrismLauncher")},
+			LauncherRoot{"PolyMC", path.Join(support, "PolyMC")},
+		)
+	case "linux":
+		share := path.Join(home, ".local", "share")
+		flatpak := path.Join(home, ".var", "app")
+		roots = append(roots,
+			LauncherRoot{"Prism Launcher", path.Join(share, "PrismLauncher")},
+			LauncherRoot{"Prism Launcher (Flatpak)",
+				path.Join(flatpak, "org.prismlauncher.PrismLauncher", "data", "PrismLauncher")},
+			LauncherRoot{"Prism Launcher (snap)",
+				path.Join(home, "snap", "prismlauncher", "current", ".local", "share", "PrismLauncher")},
+			LauncherRoot{"PolyMC", path.Join(share, "PolyMC")},
+			LauncherRoot{"PolyMC (Flatpak)", path.Join(flatpak, "org.polymc.PolyMC", "data", "PolyMC")},
+			LauncherRoot{"MultiMC", path.Join(share, "multimc")},
+			LauncherRoot{"MultiMC", path.Join(home, "MultiMC")},
+		)
+	}
+	return roots
+}
+
+// DefaultLauncherRoots return folders of launchers on this machine
+func DefaultLauncherRoots() []LauncherRoot {
+	return defaultLauncherRoots(runtime.GOOS, os.Getenv("HOME"), os.Getenv("APPDATA"))
+}
+
+// FindInstallations return game folders in roots, a folder found
+// by many roots is listed once
+func FindInstallations(roots []LauncherRoot) []*Installation {
+	ret := []*Installation{}
+	seen := map[string]bool{}
+	for _, root := range roots {
+		for _, inst := range findInRoot(root) {
+			dir, err := filepath.EvalSymlinks(inst.Dir)
+			if err != nil {
+				dir = inst.Dir
+			}
+			if seen[dir] {
+				continue
+			}
+			seen[dir] = true
+			ret = append(ret, inst)
+		}
+	}
+	return ret
+}
+
+func findInRoot(root LauncherRoot) []*Installation {
+	if !isDir(root.Dir) {
+		return nil
+	}
+	name := path.Base(root.Dir)
+	switch {
+	case isDir(path.Join(root.Dir, "saves")):
+		if root.Launcher != "" {
+			name = root.Launcher
+		}
+		return []*Installation{{
+			Name:     name,
+			Launcher: root.Launcher,
+			Dir:      root.Dir,
+			Saves:    path.Join(root.Dir, "saves"),
+		}}
+	case isDir(path.Join(root.Dir, "instances")):
+		return findInstances(root.Launcher, path.Join(root.Dir, "instances"))
+	}
+	if insts := findInstances(root.Launcher, root.Dir); len(insts) > 0 {
+		return insts
+	}
+	if worlds, _ := FindWorldDirs(root.Dir, 1); len(worlds) > 0 {
+		launcher := root.Launcher
+		if launcher == "" {
+			launcher = launcherServer
+		}
+		return []*Installation{{Name: name, Launcher: launcher, Dir: root.Dir, Saves: root.Dir}}
+	}
+	// Launcher never started
+	return nil
+}
+
+// findInstances return game folders of instances having instance.cfg in dir,
+// which are .minecraft, or minecraft in older MultiMC
+func findInstances(launcher, dir string) []*Installation {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	ret := []*Installation{}
+	for _, info := range infos {
+		instDir := path.Join(dir, info.Name())
+		cfg := path.Join(instDir, "instance.cfg")
+		if !info.IsDir() || !isFile(cfg) {
+			continue
+		}
+		gameDir := path.Join(instDir, ".minecraft")
+		if !isDir(gameDir) {
+			gameDir = path.Join(instDir, "minecraft")
+		}
+		if !isDir(gameDir) {
+			// Instance never launched
+			continue
+		}
+		name := instanceName(cfg)
+		if name == "" {
+			name = info.Name()
+		}
+		ret = append(ret, &Installation{
+			Name:     name,
+			Launcher: launcher,
+			Dir:      gameDir,
+			Saves:    path.Join(gameDir, "saves"),
+		})
+	}
+	return ret
+}
+
+// instanceName return name in instance.cfg of Prism and MultiMC
+func instanceName(cfg string) string {
+	f, err := os.Open(cfg)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if line := sc.Text(); strings.HasPrefix(line, "name=") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "name="))
+		}
+	}
+	return ""
+}
@@ -0,0 +1,92 @@
+package mc
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFindInstallations(t *testing.T) {
+	home, err := ioutil.TempDir("", "home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+
+	prism := path.Join(home, ".var/app/org.prismlauncher.PrismLauncher/data/PrismLauncher/instances")
+	multimc := path.Join(home, ".local/share/multimc/instances")
+	server := path.Join(home, "server")
+	for _, dir := range []string{
+		path.Join(home, ".minecraft/saves"),
+		path.Join(prism, "Fabric/.minecraft/saves"),
+		path.Join(prism, "New/.minecraft"),
+		path.Join(prism, "Unlaunched"),
+		path.Join(prism, "_LAUNCHER_TEMP/.minecraft"),
+		path.Join(multimc, "1.12/minecraft/saves"),
+		path.Join(server, "world"),
+		path.Join(home, "empty"),
+	} {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for fn, content := range map[string]string{
+		path.Join(prism, "Fabric/instance.cfg"):     "InstanceType=OneSix\nname=Fabric 1.20\n",
+		path.Join(prism, "New/instance.cfg"):        "InstanceType=OneSix\n",
+		path.Join(prism, "Unlaunched/instance.cfg"): "name=Unlaunched\n",
+		path.Join(multimc, "1.12/instance.cfg"):     "name=Old Times\n",
+		path.Join(server, "world/level.dat"):        "",
+		path.Join(server, "server.properties"):      "",
+	} {
+		if err := ioutil.WriteFile(fn, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	roots := defaultLauncherRoots("linux", home, "")
+	roots = append(roots,
+		LauncherRoot{Dir: server},
+		LauncherRoot{Dir: path.Join(home, "empty")},
+		// Found again by an extra root
+		LauncherRoot{Dir: path.Join(home, ".minecraft")},
+	)
+	got := FindInstallations(roots)
+	want := []*Installation{
+		{
+			Name:     "Minecraft Launcher",
+			Launcher: "Minecraft Launcher",
+			Dir:      path.Join(home, ".minecraft"),
+			Saves:    path.Join(home, ".minecraft/saves"),
+		},
+		{
+			Name:     "Fabric 1.20",
+			Launcher: "Prism Launcher (Flatpak)",
+			Dir:      path.Join(prism, "Fabric/.minecraft"),
+			Saves:    path.Join(prism, "Fabric/.minecraft/saves"),
+		},
+		{
+			Name:     "New",
+			Launcher: "Prism Launcher (Flatpak)",
+			Dir:      path.Join(prism, "New/.minecraft"),
+			Saves:    path.Join(prism, "New/.minecraft/saves"),
+		},
+		{
+			Name:     "Old Times",
+			Launcher: "MultiMC",
+			Dir:      path.Join(multimc, "1.12/minecraft"),
+			Saves:    path.Join(multimc, "1.12/minecraft/saves"),
+		},
+		{
+			Name:     "server",
+			Launcher: launcherServer,
+			Dir:      server,
+			Saves:    server,
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
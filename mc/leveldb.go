@@ -0,0 +1,371 @@
+package mc
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+// LevelDB of Bedrock worlds is read without a LevelDB library: records in
+// log files and tables compressed with zlib or raw deflate are read,
+// checksums are not checked and snappy blocks are skipped. Only data blocks
+// of tables which may hold the wanted keys are read.
+
+const (
+	levelDBTableMagic = 0xdb4775248b80fb57
+	levelDBFooterSize = 48
+	levelDBLogBlock   = 32768
+	levelDBLogHeader  = 7
+)
+
+// Log record types
+const (
+	levelDBLogFull = iota + 1
+	levelDBLogFirst
+	levelDBLogMiddle
+	levelDBLogLast
+)
+
+// Block compression types, Bedrock uses zlib and raw deflate
+const (
+	levelDBNoCompression = 0
+	levelDBSnappy        = 1
+	levelDBZlib          = 2
+	levelDBRawDeflate    = 4
+)
+
+// levelDBValueKind is kind of records setting a value, deletions are 0
+const levelDBValueKind = 1
+
+// levelDBEntry is the newest record of a key
+type levelDBEntry struct {
+	seq     uint64
+	deleted bool
+	value   []byte
+}
+
+type levelDBReader struct {
+	keys     []string // Keys to read
+	prefixes []string // Prefixes of keys to read
+	entries  map[string]*levelDBEntry
+}
+
+// readLevelDB return newest values of keys, and keys with prefixes,
+// in LevelDB folder dir
+func readLevelDB(dir string, keys, prefixes []string) (map[string][]byte, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("mc.readLevelDB: %s", err)
+	}
+	r := &levelDBReader{keys: keys, prefixes: prefixes, entries: map[string]*levelDBEntry{}}
+	for _, info := range infos {
+		fn := path.Join(dir, info.Name())
+		switch {
+		case strings.HasSuffix(fn, ".ldb"), strings.HasSuffix(fn, ".sst"):
+			err = r.readTable(fn)
+		case strings.HasSuffix(fn, ".log"):
+			err = r.readLog(fn)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("mc.readLevelDB: %s", err)
+		}
+	}
+
+	ret := map[string][]byte{}
+	for key, e := range r.entries {
+		if !e.deleted {
+			ret[key] = e.value
+		}
+	}
+	return ret, nil
+}
+
+func (r *levelDBReader) match(key string) bool {
+	for _, k := range r.keys {
+		if key == k {
+			return true
+		}
+	}
+	for _, p := range r.prefixes {
+		if strings.HasPrefix(key, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// mayHold tells whether a key to read may be in [lo, hi], lo is nil for no lower bound
+func (r *levelDBReader) mayHold(lo, hi []byte) bool {
+	for _, k := range r.keys {
+		if bytes.Compare(lo, []byte(k)) <= 0 && bytes.Compare([]byte(k), hi) <= 0 {
+			return true
+		}
+	}
+	for _, p := range r.prefixes {
+		if bytes.Compare([]byte(p), hi) <= 0 && (bytes.Compare(lo, []byte(p)) <= 0 || bytes.HasPrefix(lo, []byte(p))) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *levelDBReader) put(key []byte, seq uint64, kind byte, value []byte) {
+	k := string(key)
+	if !r.match(k) {
+		return
+	}
+	if e, ok := r.entries[k]; ok && e.seq > seq {
+		return
+	}
+	r.entries[k] = &levelDBEntry{
+		seq:     seq,
+		deleted: kind != levelDBValueKind,
+		value:   append([]byte(nil), value...),
+	}
+}
+
+// readLog read write batches in a log file, a torn record at the end is ignored
+func (r *levelDBReader) readLog(fn string) error {
+	bs, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return fmt.Errorf("mc.levelDBReader.readLog: %s", err)
+	}
+
+	var record []byte
+	for p := 0; p+levelDBLogHeader <= len(bs); {
+		left := levelDBLogBlock - p%levelDBLogBlock
+		if left < levelDBLogHeader {
+			p += left
+			continue
+		}
+		length := int(binary.LittleEndian.Uint16(bs[p+4:]))
+		kind := bs[p+6]
+		if kind == 0 && length == 0 {
+			// Preallocated space
+			p += left
+			continue
+		}
+		if p+levelDBLogHeader+length > len(bs) {
+			break
+		}
+		data := bs[p+levelDBLogHeader : p+levelDBLogHeader+length]
+		p += levelDBLogHeader + length
+
+		switch kind {
+		case levelDBLogFull:
+			record = data
+		case levelDBLogFirst:
+			record = append([]byte(nil), data...)
+			continue
+		case levelDBLogMiddle:
+			record = append(record, data...)
+			continue
+		case levelDBLogLast:
+			record = append(record, data...)
+		default:
+			return fmt.Errorf("mc.levelDBReader.readLog: %s: bad record type %d", fn, kind)
+		}
+		if err := r.readBatch(record); err != nil {
+			return fmt.Errorf("mc.levelDBReader.readLog: %s: %s", fn, err)
+		}
+		record = nil
+	}
+	return nil
+}
+
+// readBatch read a write batch: sequence, count and records
+func (r *levelDBReader) readBatch(batch []byte) error {
+	if len(batch) < 12 {
+		return fmt.Errorf("short batch")
+	}
+	seq := binary.LittleEndian.Uint64(batch)
+	count := binary.LittleEndian.Uint32(batch[8:])
+	buf := bytes.NewReader(batch[12:])
+	for i := uint32(0); i < count; i++ {
+		kind, err := buf.ReadByte()
+		if err != nil {
+			return err
+		}
+		key, err := readLevelDBSlice(buf)
+		if err != nil {
+			return err
+		}
+		var value []byte
+		if kind == levelDBValueKind {
+			if value, err = readLevelDBSlice(buf); err != nil {
+				return err
+			}
+		}
+		r.put(key, seq+uint64(i), kind, value)
+	}
+	return nil
+}
+
+func readLevelDBSlice(buf *bytes.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(buf)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(buf.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	ret := make([]byte, n)
+	_, err = io.ReadFull(buf, ret)
+	return ret, err
+}
+
+// readTable read data blocks listed in index block of a table file, which
+// may hold keys to read. Keys of the index block are user keys with a trailer
+// of 8 bytes, at least the last key of their block and less than the next block.
+func (r *levelDBReader) readTable(fn string) error {
+	f, err := os.Open(fn)
+	if err != nil {
+		return fmt.Errorf("mc.levelDBReader.readTable: %s", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("mc.levelDBReader.readTable: %s", err)
+	}
+	fileSize := uint64(info.Size())
+	footerBytes := make([]byte, levelDBFooterSize)
+	if fileSize < levelDBFooterSize {
+		return fmt.Errorf("mc.levelDBReader.readTable: %s is not a table", fn)
+	}
+	if _, err := f.ReadAt(footerBytes, int64(fileSize-levelDBFooterSize)); err != nil {
+		return fmt.Errorf("mc.levelDBReader.readTable: %s", err)
+	}
+	if binary.LittleEndian.Uint64(footerBytes[levelDBFooterSize-8:]) != levelDBTableMagic {
+		return fmt.Errorf("mc.levelDBReader.readTable: %s is not a table", fn)
+	}
+
+	footer := bytes.NewReader(footerBytes)
+	var handles [4]uint64 // Metaindex and index
+	for i := range handles {
+		if handles[i], err = binary.ReadUvarint(footer); err != nil {
+			return fmt.Errorf("mc.levelDBReader.readTable: %s: %s", fn, err)
+		}
+	}
+	index, err := levelDBBlock(f, fileSize, handles[2], handles[3])
+	if err != nil {
+		return fmt.Errorf("mc.levelDBReader.readTable: %s: index: %s", fn, err)
+	}
+
+	var lo []byte
+	return levelDBBlockEntries(index, func(separator, handle []byte) error {
+		if len(separator) < 8 {
+			return fmt.Errorf("short key")
+		}
+		hi := separator[:len(separator)-8]
+		skip := !r.mayHold(lo, hi)
+		lo = append(lo[:0:0], hi...)
+		if skip {
+			return nil
+		}
+
+		hr := bytes.NewReader(handle)
+		offset, err := binary.ReadUvarint(hr)
+		if err != nil {
+			return err
+		}
+		size, err := binary.ReadUvarint(hr)
+		if err != nil {
+			return err
+		}
+		block, err := levelDBBlock(f, fileSize, offset, size)
+		if err == errLevelDBSnappy {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("mc.levelDBReader.readTable: %s: %s", fn, err)
+		}
+		return levelDBBlockEntries(block, func(ikey, value []byte) error {
+			if len(ikey) < 8 {
+				return fmt.Errorf("short key")
+			}
+			n := len(ikey) - 8
+			trailer := binary.LittleEndian.Uint64(ikey[n:])
+			r.put(ikey[:n], trailer>>8, byte(trailer), value)
+			return nil
+		})
+	})
+}
+
+var errLevelDBSnappy = fmt.Errorf("snappy compression is not supported")
+
+// levelDBBlock return uncompressed content of block at offset in a file of
+// fileSize, the block is followed by compression type and checksum of 5 bytes
+func levelDBBlock(f io.ReaderAt, fileSize, offset, size uint64) ([]byte, error) {
+	// Checked without adding, handles of broken files may overflow
+	if offset > fileSize || fileSize-offset < 5 || size > fileSize-offset-5 {
+		return nil, fmt.Errorf("block out of file")
+	}
+	bs := make([]byte, size+1)
+	if _, err := f.ReadAt(bs, int64(offset)); err != nil {
+		return nil, err
+	}
+	data := bs[:size]
+	var rd io.Reader
+	switch bs[size] {
+	case levelDBNoCompression:
+		return data, nil
+	case levelDBZlib:
+		zr, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		rd = zr
+	case levelDBRawDeflate:
+		rd = flate.NewReader(bytes.NewReader(data))
+	case levelDBSnappy:
+		return nil, errLevelDBSnappy
+	default:
+		return nil, fmt.Errorf("unknown compression %d", bs[size])
+	}
+	return ioutil.ReadAll(rd)
+}
+
+// levelDBBlockEntries call f with keys and values in block,
+// keys share prefix with the previous one
+func levelDBBlockEntries(block []byte, f func(key, value []byte) error) error {
+	if len(block) < 4 {
+		return fmt.Errorf("short block")
+	}
+	restarts := int(binary.LittleEndian.Uint32(block[len(block)-4:]))
+	end := len(block) - 4 - 4*restarts
+	if end < 0 {
+		return fmt.Errorf("bad restarts")
+	}
+	buf := bytes.NewReader(block[:end])
+	var key []byte
+	for buf.Len() > 0 {
+		var lens [3]uint64 // Shared, not shared and value
+		for i := range lens {
+			n, err := binary.ReadUvarint(buf)
+			if err != nil {
+				return err
+			}
+			lens[i] = n
+		}
+		if lens[0] > uint64(len(key)) || lens[1]+lens[2] > uint64(buf.Len()) {
+			return fmt.Errorf("bad block entry")
+		}
+		suffix := make([]byte, lens[1])
+		io.ReadFull(buf, suffix)
+		key = append(key[:lens[0]:lens[0]], suffix...)
+		value := make([]byte, lens[2])
+		io.ReadFull(buf, value)
+		if err := f(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,401 @@
+package mc
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Column is the highest non-air block of a column
+type Column struct {
+	Block string `json:"block"`
+	Y     int    `json:"y"`
+}
+
+// netherScanTop is where scanning starts in the nether, below bedrock roof
+const netherScanTop = 120
+
+// TopBlocks return the highest non-air block of each column in ZX order,
+// Block is "" for empty column.
+// In the nether the roof is skipped, scanning starts from the first air below it.
+func (c *Chunk) TopBlocks(dim string) [256]Column {
+	var ret [256]Column
+	if len(c.Sections) == 0 {
+		return ret
+	}
+	top := c.Sections[len(c.Sections)-1].Y*16 + 15
+	bottom := c.Sections[0].Y * 16
+	if dim == DimensionNether && top > netherScanTop {
+		top = netherScanTop
+	}
+	for z := 0; z < 16; z++ {
+		for x := 0; x < 16; x++ {
+			seenAir := dim != DimensionNether
+			for y := top; y >= bottom; y-- {
+				name := c.Block(x, y, z)
+				if IsAir(name) {
+					seenAir = true
+					continue
+				}
+				if seenAir {
+					ret[z*16+x] = Column{Block: name, Y: y}
+					break
+				}
+			}
+		}
+	}
+	return ret
+}
+
+// chunkTop caches colors of a chunk from top, timestamp is when it is saved
+type chunkTop struct {
+	pos       ChunkPos
+	timestamp int64
+	columns   [256]Column
+}
+
+// MapCacheChunks is the most chunks a map renderer keeps columns of, about 25 MB.
+// A map of 256 pixels at one pixel per 2 blocks covers 1024 chunks, so maps
+// drawn at lower scales are not larger than that, or they evict their own chunks.
+const MapCacheChunks = 4096
+
+// MapRenderer renders top-down map of a dimension,
+// columns of chunks are cached until the chunk is saved again.
+// The least recently used chunks are dropped beyond MapCacheChunks.
+type MapRenderer struct {
+	world *World
+	dim   string
+
+	mutex  sync.Mutex
+	chunks map[ChunkPos]*list.Element // Values are *chunkTop
+	recent *list.List                 // Most recently used first
+}
+
+// NewMapRenderer return map renderer of a dimension
+func NewMapRenderer(world *World, dim string) *MapRenderer {
+	return &MapRenderer{
+		world:  world,
+		dim:    dim,
+		chunks: map[ChunkPos]*list.Element{},
+		recent: list.New(),
+	}
+}
+
+// cached return cached columns of chunk and mark it used, nil if not cached
+func (m *MapRenderer) cached(pos ChunkPos) *chunkTop {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	e, ok := m.chunks[pos]
+	if !ok {
+		return nil
+	}
+	m.recent.MoveToFront(e)
+	return e.Value.(*chunkTop)
+}
+
+// cache keep columns of chunk, and drop the least recently used beyond MapCacheChunks
+func (m *MapRenderer) cache(top *chunkTop) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if e, ok := m.chunks[top.pos]; ok {
+		e.Value = top
+		m.recent.MoveToFront(e)
+		return
+	}
+	m.chunks[top.pos] = m.recent.PushFront(top)
+	for m.recent.Len() > MapCacheChunks {
+		e := m.recent.Back()
+		m.recent.Remove(e)
+		delete(m.chunks, e.Value.(*chunkTop).pos)
+	}
+}
+
+// Column return top block at x, z, nil if chunk is not generated
+func (m *MapRenderer) Column(x, z int) (*Column, error) {
+	rr, err := newRegionReader(m.world.Path, m.dim)
+	if err != nil {
+		return nil, fmt.Errorf("mc.MapRenderer.Column: %s", err)
+	}
+	defer rr.close()
+
+	top, err := m.chunkTop(rr, ChunkPos{floorDiv(x, 16), floorDiv(z, 16)})
+	if err != nil || top == nil {
+		return nil, err
+	}
+	column := top.columns[floorMod(z, 16)*16+floorMod(x, 16)]
+	return &column, nil
+}
+
+// Render draws blocks [x0, x0+size*scale) x [z0, z0+size*scale),
+// one pixel per scale blocks, with north up.
+// Missing chunks are transparent. With shade, columns higher than their north
+// neighbor are brighter and lower ones are darker.
+// Drawing stops with the error of ctx when it is done.
+func (m *MapRenderer) Render(ctx context.Context, x0, z0, size, scale int, shade bool) (*image.RGBA, error) {
+	rr, err := newRegionReader(m.world.Path, m.dim)
+	if err != nil {
+		return nil, fmt.Errorf("mc.MapRenderer.Render: %s", err)
+	}
+	defer rr.close()
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	// Heights of previous row, starting from the row north of image
+	prevHeights := make([]int, size)
+	prevValid := make([]bool, size)
+	for pz := -1; pz < size; pz++ {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("mc.MapRenderer.Render: %s", err)
+		}
+		for px := 0; px < size; px++ {
+			x, z := x0+px*scale, z0+pz*scale
+			top, err := m.chunkTop(rr, ChunkPos{floorDiv(x, 16), floorDiv(z, 16)})
+			if err != nil {
+				return nil, fmt.Errorf("mc.MapRenderer.Render: %s", err)
+			}
+			if top == nil {
+				prevValid[px] = false
+				continue
+			}
+			column := top.columns[floorMod(z, 16)*16+floorMod(x, 16)]
+			if column.Block == "" {
+				prevValid[px] = false
+				continue
+			}
+			if pz >= 0 {
+				c := BlockColor(column.Block)
+				if shade && prevValid[px] {
+					if column.Y > prevHeights[px] {
+						c = scaleColor(c, 1.15)
+					} else if column.Y < prevHeights[px] {
+						c = scaleColor(c, 0.8)
+					}
+				}
+				img.SetRGBA(px, pz, c)
+			}
+			prevHeights[px] = column.Y
+			prevValid[px] = true
+		}
+	}
+	return img, nil
+}
+
+// Thumbnails are ThumbnailSize pixels wide like icon.png of worlds,
+// one pixel per thumbnailScale blocks
+const (
+	ThumbnailSize  = 64
+	thumbnailScale = 2
+)
+
+// RegionsModTime return the last modified time of regions overlapping blocks
+// [x0, x0+width) x [z0, z0+width), zero if none of them exists.
+// It is cheap to check before drawing.
+func (m *MapRenderer) RegionsModTime(x0, z0, width int) (time.Time, error) {
+	modTime := time.Time{}
+	dimDir, err := DimensionDir(m.world.Path, m.dim)
+	if err != nil {
+		return modTime, fmt.Errorf("mc.MapRenderer.RegionsModTime: %s", err)
+	}
+	regionDir := path.Join(dimDir, "region")
+	blocks := regionChunks * 16
+	for rx := floorDiv(x0, blocks); rx <= floorDiv(x0+width-1, blocks); rx++ {
+		for rz := floorDiv(z0, blocks); rz <= floorDiv(z0+width-1, blocks); rz++ {
+			fn := RegionFilename(regionDir, rx*regionChunks, rz*regionChunks)
+			info, err := os.Stat(fn)
+			if err == nil && info.ModTime().After(modTime) {
+				modTime = info.ModTime()
+			}
+		}
+	}
+	return modTime, nil
+}
+
+// ThumbnailModTime return the last modified time of regions in the thumbnail
+// around x, z, zero if none of them exists
+func (m *MapRenderer) ThumbnailModTime(x, z int) (time.Time, error) {
+	half := ThumbnailSize * thumbnailScale / 2
+	modTime, err := m.RegionsModTime(x-half, z-half, 2*half)
+	if err != nil {
+		return modTime, fmt.Errorf("mc.MapRenderer.ThumbnailModTime: %s", err)
+	}
+	return modTime, nil
+}
+
+// Thumbnail draws the map around x, z, and return the last modified time of
+// regions in it. The image is nil if no chunk around is generated.
+func (m *MapRenderer) Thumbnail(ctx context.Context, x, z int) (*image.RGBA, time.Time, error) {
+	half := ThumbnailSize * thumbnailScale / 2
+	x0, z0 := x-half, z-half
+
+	modTime, err := m.ThumbnailModTime(x, z)
+	if err != nil {
+		return nil, modTime, fmt.Errorf("mc.MapRenderer.Thumbnail: %s", err)
+	}
+	if modTime.IsZero() {
+		return nil, modTime, nil
+	}
+
+	img, err := m.Render(ctx, x0, z0, ThumbnailSize, thumbnailScale, true)
+	if err != nil {
+		return nil, modTime, fmt.Errorf("mc.MapRenderer.Thumbnail: %s", err)
+	}
+	for i := 3; i < len(img.Pix); i += 4 {
+		if img.Pix[i] != 0 {
+			return img, modTime, nil
+		}
+	}
+	return nil, modTime, nil
+}
+
+func (m *MapRenderer) chunkTop(rr *regionReader, pos ChunkPos) (*chunkTop, error) {
+	r, err := rr.region(pos)
+	if err != nil || r == nil || !r.HasChunk(pos.X, pos.Z) {
+		return nil, err
+	}
+	timestamp := r.Timestamp(pos.X, pos.Z)
+
+	if top := m.cached(pos); top != nil && top.timestamp == timestamp {
+		return top, nil
+	}
+
+	chunk, err := rr.chunk(pos)
+	if err != nil || chunk == nil {
+		return nil, err
+	}
+	top := &chunkTop{pos: pos, timestamp: timestamp, columns: chunk.TopBlocks(m.dim)}
+	m.cache(top)
+	return top, nil
+}
+
+func scaleColor(c color.RGBA, f float64) color.RGBA {
+	scale := func(v uint8) uint8 {
+		s := float64(v) * f
+		if s > 255 {
+			return 255
+		}
+		return uint8(s)
+	}
+	return color.RGBA{scale(c.R), scale(c.G), scale(c.B), c.A}
+}
+
+// blockColors maps block names without namespace to map color
+var blockColors = map[string]color.RGBA{
+	"grass_block":   {0x7f, 0xb2, 0x38, 0xff},
+	"grass":         {0x7f, 0xb2, 0x38, 0xff},
+	"tall_grass":    {0x7f, 0xb2, 0x38, 0xff},
+	"fern":          {0x70, 0xa0, 0x30, 0xff},
+	"dirt":          {0x97, 0x6d, 0x4d, 0xff},
+	"coarse_dirt":   {0x97, 0x6d, 0x4d, 0xff},
+	"podzol":        {0x81, 0x56, 0x31, 0xff},
+	"mycelium":      {0x7f, 0x3f, 0xb2, 0xff},
+	"farmland":      {0x97, 0x6d, 0x4d, 0xff},
+	"grass_path":    {0x94, 0x7a, 0x46, 0xff},
+	"dirt_path":     {0x94, 0x7a, 0x46, 0xff},
+	"stone":         {0x70, 0x70, 0x70, 0xff},
+	"cobblestone":   {0x70, 0x70, 0x70, 0xff},
+	"andesite":      {0x70, 0x70, 0x70, 0xff},
+	"diorite":       {0xff, 0xfc, 0xf5, 0xff},
+	"granite":       {0x97, 0x6d, 0x4d, 0xff},
+	"deepslate":     {0x64, 0x64, 0x64, 0xff},
+	"bedrock":       {0x50, 0x50, 0x50, 0xff},
+	"gravel":        {0x88, 0x82, 0x7f, 0xff},
+	"sand":          {0xf7, 0xe9, 0xa3, 0xff},
+	"sandstone":     {0xf7, 0xe9, 0xa3, 0xff},
+	"red_sand":      {0xd8, 0x7f, 0x33, 0xff},
+	"clay":          {0xa4, 0xa8, 0xb8, 0xff},
+	"water":         {0x40, 0x40, 0xff, 0xff},
+	"kelp":          {0x40, 0x40, 0xff, 0xff},
+	"seagrass":      {0x40, 0x40, 0xff, 0xff},
+	"lava":          {0xff, 0x50, 0x00, 0xff},
+	"ice":           {0xa0, 0xa0, 0xff, 0xff},
+	"packed_ice":    {0xa0, 0xa0, 0xff, 0xff},
+	"blue_ice":      {0xa0, 0xa0, 0xff, 0xff},
+	"snow":          {0xff, 0xff, 0xff, 0xff},
+	"snow_block":    {0xff, 0xff, 0xff, 0xff},
+	"cactus":        {0x00, 0x7c, 0x00, 0xff},
+	"sugar_cane":    {0x00, 0x7c, 0x00, 0xff},
+	"lily_pad":      {0x00, 0x7c, 0x00, 0xff},
+	"vine":          {0x00, 0x7c, 0x00, 0xff},
+	"pumpkin":       {0xd8, 0x7f, 0x33, 0xff},
+	"melon":         {0x7f, 0xcc, 0x19, 0xff},
+	"netherrack":    {0x70, 0x02, 0x00, 0xff},
+	"soul_sand":     {0x66, 0x4c, 0x33, 0xff},
+	"soul_soil":     {0x66, 0x4c, 0x33, 0xff},
+	"glowstone":     {0xf7, 0xe9, 0xa3, 0xff},
+	"nether_bricks": {0x70, 0x02, 0x00, 0xff},
+	"end_stone":     {0xf7, 0xe9, 0xa3, 0xff},
+	"obsidian":      {0x19, 0x19, 0x19, 0xff},
+	"bricks":        {0x99, 0x33, 0x33, 0xff},
+	"stone_bricks":  {0x70, 0x70, 0x70, 0xff},
+	"glass":         {0xd0, 0xe8, 0xf0, 0xff},
+	"quartz_block":  {0xff, 0xfc, 0xf5, 0xff},
+	"terracotta":    {0x98, 0x5e, 0x43, 0xff},
+	"torch":         {0xff, 0xd8, 0x00, 0xff},
+}
+
+// dyeColors is used by colored blocks like wool, concrete and glass
+var dyeColors = map[string]color.RGBA{
+	"white":      {0xff, 0xff, 0xff, 0xff},
+	"orange":     {0xd8, 0x7f, 0x33, 0xff},
+	"magenta":    {0xb2, 0x4c, 0xd8, 0xff},
+	"light_blue": {0x66, 0x99, 0xd8, 0xff},
+	"yellow":     {0xe5, 0xe5, 0x33, 0xff},
+	"lime":       {0x7f, 0xcc, 0x19, 0xff},
+	"pink":       {0xf2, 0x7f, 0xa5, 0xff},
+	"gray":       {0x4c, 0x4c, 0x4c, 0xff},
+	"light_gray": {0x99, 0x99, 0x99, 0xff},
+	"cyan":       {0x4c, 0x7f, 0x99, 0xff},
+	"purple":     {0x7f, 0x3f, 0xb2, 0xff},
+	"blue":       {0x33, 0x4c, 0xb2, 0xff},
+	"brown":      {0x66, 0x4c, 0x33, 0xff},
+	"green":      {0x66, 0x7f, 0x33, 0xff},
+	"red":        {0x99, 0x33, 0x33, 0xff},
+	"black":      {0x19, 0x19, 0x19, 0xff},
+}
+
+// blockSuffixColors is tried in order when a block is not in blockColors
+var blockSuffixColors = []struct {
+	suffix string
+	color  color.RGBA
+}{
+	{"_leaves", color.RGBA{0x00, 0x7c, 0x00, 0xff}},
+	{"_log", color.RGBA{0x66, 0x4c, 0x33, 0xff}},
+	{"_wood", color.RGBA{0x66, 0x4c, 0x33, 0xff}},
+	{"_stem", color.RGBA{0x66, 0x4c, 0x33, 0xff}},
+	{"_planks", color.RGBA{0x8f, 0x77, 0x48, 0xff}},
+	{"_slab", color.RGBA{0x8f, 0x77, 0x48, 0xff}},
+	{"_stairs", color.RGBA{0x8f, 0x77, 0x48, 0xff}},
+	{"_fence", color.RGBA{0x8f, 0x77, 0x48, 0xff}},
+	{"_ore", color.RGBA{0x70, 0x70, 0x70, 0xff}},
+	{"_flower", color.RGBA{0xe5, 0xe5, 0x33, 0xff}},
+	{"_sapling", color.RGBA{0x00, 0x7c, 0x00, 0xff}},
+	{"_coral", color.RGBA{0xf2, 0x7f, 0xa5, 0xff}},
+}
+
+// defaultBlockColor is for unknown blocks
+var defaultBlockColor = color.RGBA{0x8a, 0x8a, 0x8a, 0xff}
+
+// BlockColor return color of block on map
+func BlockColor(name string) color.RGBA {
+	name = strings.TrimPrefix(name, "minecraft:")
+	if c, ok := blockColors[name]; ok {
+		return c
+	}
+	for dye, c := range dyeColors {
+		if strings.HasPrefix(name, dye+"_") {
+			return c
+		}
+	}
+	for _, sc := range blockSuffixColors {
+		if strings.HasSuffix(name, sc.suffix) {
+			return sc.color
+		}
+	}
+	return defaultBlockColor
+}
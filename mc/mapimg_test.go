@@ -0,0 +1,141 @@
+package mc
+
+import (
+	"context"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMapRenderer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "map")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeRegion(t, path.Join(dir, "region"), 0, 0, map[ChunkPos][]byte{
+		{0, 0}: testChunk(0, 0),
+	})
+
+	m := NewMapRenderer(&World{Path: dir}, DimensionOverworld)
+	column, err := m.Column(3, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&Column{Block: "minecraft:stone", Y: 1}, column); diff != "" {
+		t.Errorf("column mismatch (-want +got):\n%s", diff)
+	}
+	if column, err := m.Column(16, 0); err != nil || column != nil {
+		t.Errorf("column of missing chunk = %v, %v", column, err)
+	}
+
+	// Blocks -8..23 with 2 blocks per pixel, only chunk (0, 0) is generated
+	img, err := m.Render(context.Background(), -8, -8, 16, 2, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stone := BlockColor("minecraft:stone")
+	for _, c := range []struct {
+		x, y int
+		want color.RGBA
+	}{
+		{0, 0, color.RGBA{}},
+		{4, 4, stone},
+		{11, 11, stone},
+		{12, 4, color.RGBA{}},
+	} {
+		if got := img.RGBAAt(c.x, c.y); got != c.want {
+			t.Errorf("pixel (%d, %d) = %v, want %v", c.x, c.y, got, c.want)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := m.Render(ctx, -8, -8, 16, 2, true); err == nil {
+		t.Error("render with cancelled context should fail")
+	}
+}
+
+func TestMapRendererCache(t *testing.T) {
+	m := NewMapRenderer(&World{}, DimensionOverworld)
+	for i := 0; i < MapCacheChunks+2; i++ {
+		m.cache(&chunkTop{pos: ChunkPos{i, 0}})
+		if i == 0 {
+			continue
+		}
+		// Chunk 0 is used again and kept
+		if m.cached(ChunkPos{0, 0}) == nil {
+			t.Fatalf("chunk 0 dropped after %d chunks", i)
+		}
+	}
+	if len(m.chunks) != MapCacheChunks || m.recent.Len() != MapCacheChunks {
+		t.Errorf("cached %d, %d chunks, want %d", len(m.chunks), m.recent.Len(), MapCacheChunks)
+	}
+	for _, pos := range []ChunkPos{{1, 0}, {2, 0}} {
+		if m.cached(pos) != nil {
+			t.Errorf("least recently used chunk %v is kept", pos)
+		}
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	dir, err := ioutil.TempDir("", "map")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m := NewMapRenderer(&World{Path: dir}, DimensionOverworld)
+	if img, _, err := m.Thumbnail(context.Background(), 0, 0); err != nil || img != nil {
+		t.Errorf("thumbnail of world without regions = %v, %v", img, err)
+	}
+	if modTime, err := m.ThumbnailModTime(0, 0); err != nil || !modTime.IsZero() {
+		t.Errorf("ThumbnailModTime of world without regions = %v, %v", modTime, err)
+	}
+
+	writeRegion(t, path.Join(dir, "region"), 0, 0, map[ChunkPos][]byte{
+		{0, 0}: testChunk(0, 0),
+	})
+	// Spawn at 8, 8 is the middle of thumbnail
+	img, modTime, err := m.Thumbnail(context.Background(), 8, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img == nil || modTime.IsZero() {
+		t.Fatalf("thumbnail = %v, %v", img, modTime)
+	}
+	if got, err := m.ThumbnailModTime(8, 8); err != nil || !got.Equal(modTime) {
+		t.Errorf("ThumbnailModTime = %v, %v, want %v", got, err, modTime)
+	}
+	half := ThumbnailSize / 2
+	if got, want := img.RGBAAt(half, half), BlockColor("minecraft:stone"); got != want {
+		t.Errorf("middle pixel = %v, want %v", got, want)
+	}
+	if got := img.RGBAAt(0, 0); got != (color.RGBA{}) {
+		t.Errorf("corner pixel = %v, want transparent", got)
+	}
+
+	// Region exists but chunks around are not generated
+	if img, _, err := m.Thumbnail(context.Background(), 400, 400); err != nil || img != nil {
+		t.Errorf("thumbnail of missing chunks = %v, %v", img, err)
+	}
+}
+
+func TestBlockColor(t *testing.T) {
+	for name, want := range map[string]color.RGBA{
+		"minecraft:water":           blockColors["water"],
+		"minecraft:light_blue_wool": dyeColors["light_blue"],
+		"minecraft:blue_concrete":   dyeColors["blue"],
+		"minecraft:birch_leaves":    {0x00, 0x7c, 0x00, 0xff},
+		"minecraft:unknown":         defaultBlockColor,
+	} {
+		if got := BlockColor(name); got != want {
+			t.Errorf("BlockColor(%s) = %v, want %v", name, got, want)
+		}
+	}
+}
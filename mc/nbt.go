@@ -0,0 +1,338 @@
+package mc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// NBT tag types
+const (
+	TagEnd byte = iota
+	TagByte
+	TagShort
+	TagInt
+	TagLong
+	TagFloat
+	TagDouble
+	TagByteArray
+	TagString
+	TagList
+	TagCompound
+	TagIntArray
+	TagLongArray
+)
+
+// Compound is a NBT compound tag.
+// Values are int8, int16, int32, int64, float32, float64, []byte, string,
+// []interface{}, Compound, []int32 or []int64 by tag type.
+type Compound map[string]interface{}
+
+// ErrNBTTagType returned when a tag type is unknown
+var ErrNBTTagType = errors.New("Unknown NBT tag type")
+
+// nbtMaxDepth limits nesting of lists and compounds of broken files
+const nbtMaxDepth = 512
+
+// ReadNBTFile read a gzip, zlib or uncompressed NBT file like level.dat
+func ReadNBTFile(filename string) (Compound, error) {
+	bs, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("mc.ReadNBTFile: %s", err)
+	}
+	root, err := DecodeNBT(bs)
+	if err != nil {
+		return nil, fmt.Errorf("mc.ReadNBTFile: %s: %s", filename, err)
+	}
+	return root, nil
+}
+
+// DecodeNBT decode big-endian NBT data, compression is detected
+func DecodeNBT(data []byte) (Compound, error) {
+	var reader io.Reader = bytes.NewReader(data)
+	var err error
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		reader, err = gzip.NewReader(reader)
+	} else if len(data) >= 2 && data[0] == 0x78 {
+		reader, err = zlib.NewReader(reader)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("mc.DecodeNBT: %s", err)
+	}
+	root, err := newNBTDecoder(reader, binary.BigEndian).decodeRoot()
+	if err != nil {
+		return nil, fmt.Errorf("mc.DecodeNBT: %s", err)
+	}
+	return root, nil
+}
+
+type nbtDecoder struct {
+	r     *bufio.Reader
+	order binary.ByteOrder
+	depth int
+}
+
+func newNBTDecoder(r io.Reader, order binary.ByteOrder) *nbtDecoder {
+	return &nbtDecoder{r: bufio.NewReader(r), order: order}
+}
+
+// decodeRoot read the unnamed root compound
+func (d *nbtDecoder) decodeRoot() (Compound, error) {
+	tagType, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if tagType != TagCompound {
+		return nil, fmt.Errorf("root tag type %d is not compound", tagType)
+	}
+	if _, err := d.readString(); err != nil {
+		return nil, err
+	}
+	return d.readCompound()
+}
+
+func (d *nbtDecoder) readPayload(tagType byte) (interface{}, error) {
+	switch tagType {
+	case TagByte:
+		b, err := d.r.ReadByte()
+		return int8(b), err
+	case TagShort:
+		var v int16
+		err := binary.Read(d.r, d.order, &v)
+		return v, err
+	case TagInt:
+		var v int32
+		err := binary.Read(d.r, d.order, &v)
+		return v, err
+	case TagLong:
+		var v int64
+		err := binary.Read(d.r, d.order, &v)
+		return v, err
+	case TagFloat:
+		var v uint32
+		err := binary.Read(d.r, d.order, &v)
+		return math.Float32frombits(v), err
+	case TagDouble:
+		var v uint64
+		err := binary.Read(d.r, d.order, &v)
+		return math.Float64frombits(v), err
+	case TagByteArray:
+		n, err := d.readLength()
+		if err != nil {
+			return nil, err
+		}
+		v := make([]byte, n)
+		_, err = io.ReadFull(d.r, v)
+		return v, err
+	case TagString:
+		return d.readString()
+	case TagList:
+		return d.readList()
+	case TagCompound:
+		return d.readCompound()
+	case TagIntArray:
+		n, err := d.readLength()
+		if err != nil {
+			return nil, err
+		}
+		v := make([]int32, n)
+		err = binary.Read(d.r, d.order, v)
+		return v, err
+	case TagLongArray:
+		n, err := d.readLength()
+		if err != nil {
+			return nil, err
+		}
+		v := make([]int64, n)
+		err = binary.Read(d.r, d.order, v)
+		return v, err
+	}
+	return nil, fmt.Errorf("%s: %d", ErrNBTTagType, tagType)
+}
+
+func (d *nbtDecoder) readLength() (int, error) {
+	var n int32
+	if err := binary.Read(d.r, d.order, &n); err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("negative length %d", n)
+	}
+	// Avoid allocating huge slice for broken files
+	if n > 1<<24 {
+		return 0, fmt.Errorf("length %d too large", n)
+	}
+	return int(n), nil
+}
+
+func (d *nbtDecoder) readString() (string, error) {
+	var n uint16
+	if err := binary.Read(d.r, d.order, &n); err != nil {
+		return "", err
+	}
+	bs := make([]byte, n)
+	if _, err := io.ReadFull(d.r, bs); err != nil {
+		return "", err
+	}
+	return decodeModifiedUTF8(bs), nil
+}
+
+// decodeModifiedUTF8 convert modified UTF-8 of java into UTF-8. It differs only
+// for NUL, which is C0 80, and supplementary characters, which are surrogate
+// pairs of 3 bytes each. Other invalid bytes are kept.
+func decodeModifiedUTF8(bs []byte) string {
+	if utf8.Valid(bs) {
+		return string(bs)
+	}
+	// surrogate return the surrogate encoded at bs[i:i+3], or 0
+	surrogate := func(i int) rune {
+		if i+2 >= len(bs) || bs[i] != 0xED || bs[i+1]&0xE0 != 0xA0 || bs[i+2]&0xC0 != 0x80 {
+			return 0
+		}
+		return 0xD000 | rune(bs[i+1]&0x3F)<<6 | rune(bs[i+2]&0x3F)
+	}
+	ret := make([]byte, 0, len(bs))
+	for i := 0; i < len(bs); {
+		if bs[i] == 0xC0 && i+1 < len(bs) && bs[i+1] == 0x80 {
+			ret = append(ret, 0)
+			i += 2
+			continue
+		}
+		if hi := surrogate(i); hi != 0 {
+			if r := utf16.DecodeRune(hi, surrogate(i+3)); r != utf8.RuneError {
+				ret = append(ret, string(r)...)
+				i += 6
+				continue
+			}
+		}
+		ret = append(ret, bs[i])
+		i++
+	}
+	return string(ret)
+}
+
+func (d *nbtDecoder) readList() ([]interface{}, error) {
+	d.depth++
+	defer func() { d.depth-- }()
+	if d.depth > nbtMaxDepth {
+		return nil, fmt.Errorf("nested too deep")
+	}
+
+	elemType, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	n, err := d.readLength()
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		v, err := d.readPayload(elemType)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, v)
+	}
+	return ret, nil
+}
+
+func (d *nbtDecoder) readCompound() (Compound, error) {
+	d.depth++
+	defer func() { d.depth-- }()
+	if d.depth > nbtMaxDepth {
+		return nil, fmt.Errorf("nested too deep")
+	}
+
+	ret := Compound{}
+	for {
+		tagType, err := d.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if tagType == TagEnd {
+			return ret, nil
+		}
+		name, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.readPayload(tagType)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err)
+		}
+		ret[name] = v
+	}
+}
+
+// Compound returns child compound, nil if not exists
+func (c Compound) Compound(key string) Compound {
+	v, _ := c[key].(Compound)
+	return v
+}
+
+// List returns child list, nil if not exists
+func (c Compound) List(key string) []interface{} {
+	v, _ := c[key].([]interface{})
+	return v
+}
+
+// String returns child string, "" if not exists
+func (c Compound) String(key string) string {
+	v, _ := c[key].(string)
+	return v
+}
+
+// Int returns child of any integer type as int64, 0 if not exists
+func (c Compound) Int(key string) int64 {
+	return nbtInt(c[key])
+}
+
+// Float returns child of any number type as float64, 0 if not exists
+func (c Compound) Float(key string) float64 {
+	return nbtFloat(c[key])
+}
+
+// Bool returns whether child byte is not 0
+func (c Compound) Bool(key string) bool {
+	return c.Int(key) != 0
+}
+
+// Has check whether key exists
+func (c Compound) Has(key string) bool {
+	_, ok := c[key]
+	return ok
+}
+
+func nbtInt(v interface{}) int64 {
+	switch v := v.(type) {
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	}
+	return 0
+}
+
+func nbtFloat(v interface{}) float64 {
+	switch v := v.(type) {
+	case float32:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return float64(nbtInt(v))
+}
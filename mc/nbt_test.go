@@ -0,0 +1,165 @@
+package mc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// nbtWriter builds NBT data for tests
+type nbtWriter struct {
+	bytes.Buffer
+	order binary.ByteOrder
+}
+
+func (w *nbtWriter) name(tagType byte, name string) *nbtWriter {
+	w.WriteByte(tagType)
+	binary.Write(w, w.order, uint16(len(name)))
+	w.WriteString(name)
+	return w
+}
+
+func (w *nbtWriter) value(v interface{}) *nbtWriter {
+	switch v := v.(type) {
+	case string:
+		binary.Write(w, w.order, uint16(len(v)))
+		w.WriteString(v)
+	default:
+		binary.Write(w, w.order, v)
+	}
+	return w
+}
+
+func (w *nbtWriter) end() *nbtWriter {
+	w.WriteByte(TagEnd)
+	return w
+}
+
+func testLevelDat(order binary.ByteOrder) []byte {
+	w := &nbtWriter{order: order}
+	w.name(TagCompound, "")
+	w.name(TagCompound, "Data")
+	w.name(TagString, "LevelName").value("My World")
+	w.name(TagInt, "DataVersion").value(int32(1976))
+	w.name(TagCompound, "Version").name(TagString, "Name").value("1.14.4").end()
+	w.name(TagInt, "SpawnX").value(int32(-10))
+	w.name(TagInt, "SpawnY").value(int32(64))
+	w.name(TagInt, "SpawnZ").value(int32(300))
+	w.name(TagInt, "GameType").value(int32(1))
+	w.name(TagByte, "hardcore").value(int8(1))
+	w.name(TagLong, "LastPlayed").value(int64(1570000000000))
+	w.name(TagLong, "DayTime").value(int64(6000))
+	w.name(TagByte, "raining").value(int8(1))
+	w.name(TagByte, "thundering").value(int8(0))
+	w.name(TagList, "Pos").value(TagDouble).value(int32(2)).value(1.5).value(-2.5)
+	w.name(TagFloat, "Yaw").value(float32(90))
+	w.name(TagShort, "Short").value(int16(-3))
+	w.name(TagByteArray, "Bytes").value(int32(2)).value([]byte{1, 2})
+	w.name(TagIntArray, "Ints").value(int32(2)).value([]int32{3, -4})
+	w.name(TagLongArray, "Longs").value(int32(1)).value([]int64{1 << 40})
+	w.end()
+	w.end()
+	return w.Bytes()
+}
+
+func compress(t *testing.T, data []byte, newWriter func(io.Writer) io.WriteCloser) []byte {
+	var buf bytes.Buffer
+	w := newWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestDecodeNBT(t *testing.T) {
+	raw := testLevelDat(binary.BigEndian)
+	for name, data := range map[string][]byte{
+		"raw":  raw,
+		"gzip": compress(t, raw, func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }),
+		"zlib": compress(t, raw, func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }),
+	} {
+		root, err := DecodeNBT(data)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		data := root.Compound("Data")
+		want := Compound{
+			"Pos":   []interface{}{1.5, -2.5},
+			"Yaw":   float32(90),
+			"Short": int16(-3),
+			"Bytes": []byte{1, 2},
+			"Ints":  []int32{3, -4},
+			"Longs": []int64{1 << 40},
+		}
+		for key, value := range want {
+			if diff := cmp.Diff(value, data[key]); diff != "" {
+				t.Errorf("%s %s mismatch (-want +got):\n%s", name, key, diff)
+			}
+		}
+	}
+
+	if _, err := DecodeNBT(raw[:len(raw)-3]); err == nil {
+		t.Error("truncated data should fail")
+	}
+}
+
+func TestReadLevelData(t *testing.T) {
+	root, err := DecodeNBT(testLevelDat(binary.BigEndian))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got World
+	got.readLevelData(root.Compound("Data"))
+
+	want := World{
+		Name:        "My World",
+		Version:     "1.14.4",
+		DataVersion: 1976,
+		Spawn:       [3]int{-10, 64, 300},
+		GameMode:    "creative",
+		Hardcore:    true,
+		LastPlayed:  1570000000000,
+		DayTime:     6000,
+		Raining:     true,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDecodeModifiedUTF8(t *testing.T) {
+	for _, c := range []struct {
+		in   []byte
+		want string
+	}{
+		{[]byte("plain é"), "plain é"},
+		{[]byte{'a', 0xC0, 0x80, 'b'}, "a\x00b"},
+		// U+1F600 as surrogate pair D83D DE00
+		{[]byte{'<', 0xED, 0xA0, 0xBD, 0xED, 0xB8, 0x80, '>'}, "<\U0001F600>"},
+		// Lone surrogate is kept
+		{[]byte{0xED, 0xA0, 0xBD, 'x'}, "\xED\xA0\xBDx"},
+	} {
+		if got := decodeModifiedUTF8(c.in); got != c.want {
+			t.Errorf("decodeModifiedUTF8(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+
+	w := &nbtWriter{order: binary.BigEndian}
+	w.name(TagCompound, "")
+	w.name(TagString, "LevelName").value("w\xC0\x80x")
+	w.end()
+	root, err := DecodeNBT(w.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := root.String("LevelName"); got != "w\x00x" {
+		t.Errorf("LevelName = %q", got)
+	}
+}
@@ -0,0 +1,82 @@
+package mc
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Defaults of options.txt, used when a key is missing
+const (
+	DefaultFOV            = 70
+	DefaultRenderDistance = 12
+)
+
+// GameOptions is the view of the game in options.txt of a game folder
+type GameOptions struct {
+	Path string `json:"path"`
+	// FOV is vertical field of view in degrees, from 30 to 110
+	FOV float64 `json:"fov"`
+	// RenderDistance is in chunks
+	RenderDistance int `json:"render_distance"`
+	// GUIScale is 0 for auto
+	GUIScale int `json:"gui_scale"`
+	// Raw values of keys read from the file, keys missing use defaults
+	Values map[string]string `json:"values"`
+}
+
+// ReadGameOptions read options.txt in game folder dir
+func ReadGameOptions(dir string) (*GameOptions, error) {
+	fn := path.Join(dir, "options.txt")
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, fmt.Errorf("mc.ReadGameOptions: %s", err)
+	}
+	defer f.Close()
+
+	opts := &GameOptions{
+		Path:           fn,
+		FOV:            DefaultFOV,
+		RenderDistance: DefaultRenderDistance,
+		Values:         map[string]string{},
+	}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		kv := strings.SplitN(sc.Text(), ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key, value := kv[0], strings.TrimSpace(kv[1])
+		switch key {
+		case "fov":
+			// Stored as -1 ~ 1 for 30 ~ 110 degrees
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("mc.ReadGameOptions: %s: bad fov %s", fn, value)
+			}
+			opts.FOV = DefaultFOV + v*40
+		case "renderDistance":
+			v, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("mc.ReadGameOptions: %s: bad renderDistance %s", fn, value)
+			}
+			opts.RenderDistance = v
+		case "guiScale":
+			v, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("mc.ReadGameOptions: %s: bad guiScale %s", fn, value)
+			}
+			opts.GUIScale = v
+		default:
+			continue
+		}
+		opts.Values[key] = value
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("mc.ReadGameOptions: %s", err)
+	}
+	return opts, nil
+}
@@ -0,0 +1,48 @@
+package mc
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadGameOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "options")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fn := path.Join(dir, "options.txt")
+	content := "version:3465\nfov:0.25\nguiScale:3\nlang:en_us\nkey_key.jump:key.keyboard.space\n"
+	if err := ioutil.WriteFile(fn, []byte(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadGameOptions(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &GameOptions{
+		Path:           fn,
+		FOV:            80,
+		RenderDistance: DefaultRenderDistance,
+		GUIScale:       3,
+		Values:         map[string]string{"fov": "0.25", "guiScale": "3"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := ioutil.WriteFile(fn, []byte("fov:wide\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadGameOptions(dir); err == nil {
+		t.Error("bad fov is not reported")
+	}
+	if _, err := ReadGameOptions(path.Join(dir, "missing")); err == nil {
+		t.Error("missing options.txt is not reported")
+	}
+}
@@ -0,0 +1,241 @@
+package mc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// uuidRegexp matches UUIDs in 8-4-4-4-12 hex, like names of playerdata files
+var uuidRegexp = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Player stores where a player is in a world
+type Player struct {
+	UUID      string     `json:"uuid"`
+	Name      string     `json:"name"` // UUID if name is not resolved
+	Resolved  bool       `json:"resolved"`
+	Pos       [3]float64 `json:"pos"`       // X, Y, Z of feet
+	Rotation  [2]float32 `json:"rotation"`  // Yaw and pitch in degree
+	Dimension string     `json:"dimension"` // Like "minecraft:overworld"
+	LastSeen  int64      `json:"last_seen"` // Unix time in milliseconds
+
+	// Host is the singleplayer player stored in level.dat
+	Host bool `json:"host"`
+}
+
+// Vanilla dimension IDs
+const (
+	DimensionOverworld = "minecraft:overworld"
+	DimensionNether    = "minecraft:the_nether"
+	DimensionEnd       = "minecraft:the_end"
+)
+
+// hostName is the name of singleplayer host whose UUID is unknown
+const hostName = "Host"
+
+// NewPlayer read player from player NBT compound
+func NewPlayer(uuid string, data Compound) *Player {
+	p := &Player{UUID: uuid}
+	pos := data.List("Pos")
+	for i := 0; i < 3 && i < len(pos); i++ {
+		p.Pos[i] = nbtFloat(pos[i])
+	}
+	rotation := data.List("Rotation")
+	for i := 0; i < 2 && i < len(rotation); i++ {
+		p.Rotation[i] = float32(nbtFloat(rotation[i]))
+	}
+	p.Dimension = dimensionName(data["Dimension"])
+	return p
+}
+
+// dimensionName convert Dimension of player, which is an int before 1.16
+func dimensionName(v interface{}) string {
+	if name, ok := v.(string); ok {
+		return name
+	}
+	switch nbtInt(v) {
+	case -1:
+		return DimensionNether
+	case 1:
+		return DimensionEnd
+	}
+	return DimensionOverworld
+}
+
+// nbtUUID return UUID in a compound, stored as int array since 1.16
+// and as UUIDMost/UUIDLeast before
+func nbtUUID(data Compound) string {
+	var most, least uint64
+	if ints, ok := data["UUID"].([]int32); ok && len(ints) == 4 {
+		most = uint64(uint32(ints[0]))<<32 | uint64(uint32(ints[1]))
+		least = uint64(uint32(ints[2]))<<32 | uint64(uint32(ints[3]))
+	} else if data.Has("UUIDMost") {
+		most = uint64(data.Int("UUIDMost"))
+		least = uint64(data.Int("UUIDLeast"))
+	} else {
+		return ""
+	}
+	s := fmt.Sprintf("%016x%016x", most, least)
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:32]
+}
+
+// listPlayers read players in playerdata and the host in level.dat
+func listPlayers(mcDir string, levelData Compound) ([]*Player, error) {
+	playerDir := path.Join(mcDir, "playerdata")
+	files, err := ioutil.ReadDir(playerDir)
+	if os.IsNotExist(err) {
+		// Worlds never joined and Bukkit dimension folders have no playerdata
+		files = nil
+	} else if err != nil {
+		return nil, fmt.Errorf("app.listPlayers: %s", err)
+	}
+
+	// Host in level.dat is newer than its file in playerdata
+	var host *Player
+	if hostData := levelData.Compound("Player"); hostData != nil {
+		host = NewPlayer(nbtUUID(hostData), hostData)
+		host.Host = true
+		host.LastSeen = levelData.Int("LastPlayed")
+	}
+
+	players := []*Player{}
+	for _, file := range files {
+		fn := file.Name()
+		if file.IsDir() || !strings.HasSuffix(fn, ".dat") {
+			continue
+		}
+		uuid := strings.TrimSuffix(fn, ".dat")
+		if host != nil && host.UUID == uuid {
+			continue
+		}
+
+		data, err := ReadNBTFile(path.Join(playerDir, fn))
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		player := NewPlayer(uuid, data)
+		player.LastSeen = file.ModTime().UnixNano() / 1e6
+
+		player.resolveName()
+		players = append(players, player)
+	}
+
+	if host != nil {
+		if host.UUID != "" {
+			host.resolveName()
+		} else {
+			host.Name = hostName
+		}
+		players = append([]*Player{host}, players...)
+	}
+
+	return players, nil
+}
+
+// FindPlayer return the player listed as name in world folder dir, by reading
+// level.dat and the player's file in playerdata only. Players listed by UUID
+// and offline-mode players are found without resolving names, others are
+// found by resolving UUIDs of playerdata files until name matches.
+func FindPlayer(dir, name string) (*Player, error) {
+	// Worlds never saved by the game have no level.dat
+	var levelData Compound
+	if bs, err := ioutil.ReadFile(path.Join(dir, "level.dat")); err == nil {
+		root, err := DecodeNBT(bs)
+		if err != nil {
+			return nil, fmt.Errorf("mc.FindPlayer: %s", err)
+		}
+		levelData = root.Compound("Data")
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("mc.FindPlayer: %s", err)
+	}
+	var host *Player
+	if hostData := levelData.Compound("Player"); hostData != nil {
+		host = NewPlayer(nbtUUID(hostData), hostData)
+		host.Host = true
+		host.LastSeen = levelData.Int("LastPlayed")
+	}
+
+	// readPlayer return player of uuid, the host is newer than its file.
+	// Only UUIDs are file names, so names like ../x can not open other files.
+	playerDir := path.Join(dir, "playerdata")
+	readPlayer := func(uuid string) (*Player, error) {
+		if !uuidRegexp.MatchString(uuid) {
+			return nil, fmt.Errorf("bad UUID %s", uuid)
+		}
+		if host != nil && strings.EqualFold(host.UUID, uuid) {
+			host.Name = name
+			return host, nil
+		}
+		fn := path.Join(playerDir, uuid+".dat")
+		info, err := os.Stat(fn)
+		if err != nil {
+			return nil, err
+		}
+		data, err := ReadNBTFile(fn)
+		if err != nil {
+			return nil, err
+		}
+		player := NewPlayer(uuid, data)
+		player.Name = name
+		player.LastSeen = info.ModTime().UnixNano() / 1e6
+		return player, nil
+	}
+
+	if host != nil && host.UUID == "" && name == hostName {
+		host.Name = hostName
+		return host, nil
+	}
+	uuids := []string{OfflineUUID(name)}
+	if uuidRegexp.MatchString(name) {
+		// Players whose names are not resolved are listed by UUID
+		uuids = []string{name, OfflineUUID(name)}
+	}
+	for _, uuid := range uuids {
+		if player, err := readPlayer(uuid); err == nil {
+			return player, nil
+		}
+	}
+	if host != nil && host.UUID != "" {
+		if resolved, err := uuidToName(host.UUID); err == nil && resolved == name {
+			return readPlayer(host.UUID)
+		}
+	}
+
+	files, err := ioutil.ReadDir(playerDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("mc.FindPlayer: %s", err)
+	}
+	for _, file := range files {
+		fn := file.Name()
+		if file.IsDir() || !strings.HasSuffix(fn, ".dat") {
+			continue
+		}
+		uuid := strings.TrimSuffix(fn, ".dat")
+		if resolved, err := uuidToName(uuid); err == nil && resolved == name {
+			player, err := readPlayer(uuid)
+			if err != nil {
+				return nil, fmt.Errorf("mc.FindPlayer: %s", err)
+			}
+			return player, nil
+		}
+	}
+	return nil, fmt.Errorf("mc.FindPlayer: player %s not found", name)
+}
+
+// resolveName set Name, or list the player by UUID if it is unknown
+func (p *Player) resolveName() {
+	name, err := uuidToName(p.UUID)
+	if err != nil {
+		log.Println(err)
+		p.Name = p.UUID
+		p.Resolved = false
+		return
+	}
+	p.Name = name
+	p.Resolved = true
+}
@@ -0,0 +1,149 @@
+package mc
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewPlayer(t *testing.T) {
+	for _, c := range []struct {
+		data Compound
+		want *Player
+	}{
+		{
+			// Before 1.16
+			data: Compound{
+				"Pos":       []interface{}{1.5, 64.0, -3.25},
+				"Rotation":  []interface{}{float32(-90), float32(12.5)},
+				"Dimension": int32(-1),
+				"UUIDMost":  int64(-6284535011863541043),
+				"UUIDLeast": int64(-7165872825017140437),
+			},
+			want: &Player{
+				UUID:      "a8c8d8b4-1f5e-4acd-9c8d-b4a1cf3a7b2b",
+				Pos:       [3]float64{1.5, 64, -3.25},
+				Rotation:  [2]float32{-90, 12.5},
+				Dimension: DimensionNether,
+			},
+		},
+		{
+			data: Compound{
+				"Pos":       []interface{}{0.0, 70.0, 0.0},
+				"Rotation":  []interface{}{float32(0), float32(0)},
+				"Dimension": "minecraft:the_end",
+				"UUID":      []int32{-1463232332, 526273229, -1668434783, -818250965},
+			},
+			want: &Player{
+				UUID:      "a8c8d8b4-1f5e-4acd-9c8d-b4a1cf3a7b2b",
+				Pos:       [3]float64{0, 70, 0},
+				Dimension: DimensionEnd,
+			},
+		},
+	} {
+		got := NewPlayer(nbtUUID(c.data), c.data)
+		if diff := cmp.Diff(c.want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	}
+}
+
+// countResolver resolves names in a map and counts lookups
+type countResolver struct {
+	names map[string]string
+	calls int
+}
+
+func (r *countResolver) ResolveName(uuid string) (string, error) {
+	r.calls++
+	if name, ok := r.names[uuid]; ok {
+		return name, nil
+	}
+	return "", ErrNameNotFound
+}
+
+func testPlayerDat(x, y, z float64, dim string) []byte {
+	w := &nbtWriter{order: binary.BigEndian}
+	w.name(TagCompound, "")
+	w.name(TagList, "Pos").value(TagDouble).value(int32(3)).value(x).value(y).value(z)
+	w.name(TagString, "Dimension").value(dim)
+	w.end()
+	return w.Bytes()
+}
+
+func TestFindPlayer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "player")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	w := &nbtWriter{order: binary.BigEndian}
+	w.name(TagCompound, "")
+	w.name(TagCompound, "Data")
+	w.name(TagLong, "LastPlayed").value(int64(1570000000000))
+	w.name(TagCompound, "Player")
+	w.name(TagList, "Pos").value(TagDouble).value(int32(3)).value(1.0).value(2.0).value(3.0)
+	w.end() // Player
+	w.end() // Data
+	w.end()
+
+	alex := "00000000-0000-4000-8000-000000000001"
+	files := map[string][]byte{
+		"level.dat":                   w.Bytes(),
+		"playerdata/" + alex + ".dat": testPlayerDat(10, 64, 10, DimensionNether),
+		"playerdata/00000000-0000-4000-8000-0000000000ff.dat": testPlayerDat(0, 0, 0, DimensionOverworld),
+		"playerdata/" + OfflineUUID("Steve") + ".dat":         testPlayerDat(-5, 70, 5, DimensionEnd),
+	}
+	if err := os.MkdirAll(path.Join(dir, "playerdata"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	for fn, data := range files {
+		if err := ioutil.WriteFile(path.Join(dir, fn), data, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resolver := &countResolver{names: map[string]string{alex: "Alex"}}
+	SetNameResolver(resolver)
+	defer SetNameResolver(&ChainResolver{})
+
+	for name, want := range map[string]struct {
+		pos [3]float64
+		dim string
+	}{
+		hostName: {[3]float64{1, 2, 3}, DimensionOverworld},
+		"Alex":   {[3]float64{10, 64, 10}, DimensionNether},
+		"Steve":  {[3]float64{-5, 70, 5}, DimensionEnd},
+		// Listed by UUID when the name is not resolved
+		"00000000-0000-4000-8000-0000000000ff": {[3]float64{0, 0, 0}, DimensionOverworld},
+	} {
+		player, err := FindPlayer(dir, name)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		if player.Name != name || player.Pos != want.pos || player.Dimension != want.dim {
+			t.Errorf("%s: got %+v", name, player)
+		}
+	}
+	// Only Alex needs a name lookup, which stops at the first file
+	if resolver.calls != 1 {
+		t.Errorf("names resolved %d times, want 1", resolver.calls)
+	}
+	if _, err := FindPlayer(dir, "Nobody"); err == nil {
+		t.Error("unknown player is found")
+	}
+
+	// Names are not paths out of playerdata
+	if err := ioutil.WriteFile(path.Join(dir, "outside.dat"), testPlayerDat(0, 0, 0, DimensionOverworld), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FindPlayer(dir, "../outside"); err == nil {
+		t.Error("file outside playerdata is read")
+	}
+}
@@ -0,0 +1,157 @@
+package mc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+)
+
+// ErrChunkNotExist returned when reading a chunk which is not generated
+var ErrChunkNotExist = errors.New("Chunk not exist")
+
+const (
+	regionSectorSize = 4096
+	regionChunks     = 32 // Chunks per side of a region
+)
+
+// Region is an anvil region file, r.<x>.<z>.mca, storing 32x32 chunks
+type Region struct {
+	X, Z int
+
+	filename   string
+	file       *os.File
+	locations  [regionChunks * regionChunks]uint32
+	timestamps [regionChunks * regionChunks]uint32
+}
+
+// RegionFilename return filename of the region containing chunk (cx, cz)
+func RegionFilename(regionDir string, cx, cz int) string {
+	return path.Join(regionDir, fmt.Sprintf("r.%d.%d.mca", floorDiv(cx, regionChunks), floorDiv(cz, regionChunks)))
+}
+
+// OpenRegion open a region file and read its header
+func OpenRegion(filename string) (*Region, error) {
+	r := &Region{filename: filename}
+	if _, err := fmt.Sscanf(path.Base(filename), "r.%d.%d.mca", &r.X, &r.Z); err != nil {
+		return nil, fmt.Errorf("mc.OpenRegion: bad region filename %s", filename)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("mc.OpenRegion: %s", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("mc.OpenRegion: %s", err)
+	}
+	// Region files of chunks not saved yet are empty
+	if info.Size() >= 2*regionSectorSize {
+		if err := binary.Read(file, binary.BigEndian, &r.locations); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("mc.OpenRegion: %s: %s", filename, err)
+		}
+		if err := binary.Read(file, binary.BigEndian, &r.timestamps); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("mc.OpenRegion: %s: %s", filename, err)
+		}
+	}
+	r.file = file
+	return r, nil
+}
+
+// Close the region file
+func (r *Region) Close() error {
+	return r.file.Close()
+}
+
+// regionIndex return index in header of chunk (cx, cz) in world coordinate
+func regionIndex(cx, cz int) int {
+	return floorMod(cx, regionChunks) + floorMod(cz, regionChunks)*regionChunks
+}
+
+// HasChunk check whether chunk (cx, cz) in world coordinate is generated
+func (r *Region) HasChunk(cx, cz int) bool {
+	return r.locations[regionIndex(cx, cz)] != 0
+}
+
+// Timestamp return last saved time of chunk in unix seconds
+func (r *Region) Timestamp(cx, cz int) int64 {
+	return int64(r.timestamps[regionIndex(cx, cz)])
+}
+
+// ReadChunk read NBT of chunk (cx, cz) in world coordinate
+func (r *Region) ReadChunk(cx, cz int) (Compound, error) {
+	location := r.locations[regionIndex(cx, cz)]
+	if location == 0 {
+		return nil, ErrChunkNotExist
+	}
+	offset := int64(location>>8) * regionSectorSize
+	sectors := int(location & 0xff)
+
+	buf := make([]byte, sectors*regionSectorSize)
+	n, err := r.file.ReadAt(buf, offset)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("mc.Region.ReadChunk: %s", err)
+	}
+	buf = buf[:n]
+	if len(buf) < 5 {
+		return nil, fmt.Errorf("mc.Region.ReadChunk: chunk (%d, %d) truncated", cx, cz)
+	}
+
+	length := int(binary.BigEndian.Uint32(buf[0:4]))
+	compression := buf[4]
+	var data []byte
+	if compression&0x80 != 0 {
+		// Oversized chunk is stored in c.<x>.<z>.mcc
+		mcc := path.Join(path.Dir(r.filename), fmt.Sprintf("c.%d.%d.mcc", cx, cz))
+		data, err = ioutil.ReadFile(mcc)
+		if err != nil {
+			return nil, fmt.Errorf("mc.Region.ReadChunk: %s", err)
+		}
+		compression &= 0x7f
+	} else {
+		if length < 1 || 4+length > len(buf) {
+			return nil, fmt.Errorf("mc.Region.ReadChunk: chunk (%d, %d) bad length %d", cx, cz, length)
+		}
+		data = buf[5 : 4+length]
+	}
+
+	var reader io.Reader = bytes.NewReader(data)
+	switch compression {
+	case 1:
+		reader, err = gzip.NewReader(reader)
+	case 2:
+		reader, err = zlib.NewReader(reader)
+	case 3:
+	default:
+		return nil, fmt.Errorf("mc.Region.ReadChunk: unsupported compression %d", compression)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("mc.Region.ReadChunk: %s", err)
+	}
+
+	root, err := newNBTDecoder(reader, binary.BigEndian).decodeRoot()
+	if err != nil {
+		return nil, fmt.Errorf("mc.Region.ReadChunk: chunk (%d, %d): %s", cx, cz, err)
+	}
+	return root, nil
+}
+
+func floorDiv(a, b int) int {
+	if a < 0 {
+		return -((-a + b - 1) / b)
+	}
+	return a / b
+}
+
+func floorMod(a, b int) int {
+	return a - floorDiv(a, b)*b
+}
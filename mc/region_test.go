@@ -0,0 +1,250 @@
+package mc
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// testChunk builds a 1.18 chunk whose blocks below y=2 are stone
+func testChunk(cx, cz int) []byte {
+	return testStatusChunk(cx, cz, "")
+}
+
+// testStatusChunk builds the chunk of testChunk with generation status
+func testStatusChunk(cx, cz int, status string) []byte {
+	longs := make([]int64, 256)
+	for i := 0; i < 2*256; i++ {
+		// 4 bits per block, 16 blocks per long, palette index 1 is stone
+		longs[i/16] |= 1 << uint((i%16)*4)
+	}
+
+	w := &nbtWriter{order: binary.BigEndian}
+	w.name(TagCompound, "")
+	w.name(TagInt, "DataVersion").value(int32(2975))
+	w.name(TagInt, "xPos").value(int32(cx))
+	w.name(TagInt, "zPos").value(int32(cz))
+	if status != "" {
+		w.name(TagString, "Status").value(status)
+	}
+	w.name(TagList, "sections").value(TagCompound).value(int32(1))
+	w.name(TagByte, "Y").value(int8(0))
+	w.name(TagCompound, "block_states")
+	w.name(TagList, "palette").value(TagCompound).value(int32(2))
+	w.name(TagString, "Name").value(BlockAir).end()
+	w.name(TagString, "Name").value("minecraft:stone").end()
+	w.name(TagLongArray, "data").value(int32(len(longs))).value(longs)
+	w.end() // block_states
+	w.end() // section
+	w.end()
+	return w.Bytes()
+}
+
+// writeRegion writes chunks into region file of dir
+func writeRegion(t *testing.T, dir string, rx, rz int, chunks map[ChunkPos][]byte) {
+	var header [2 * regionSectorSize]byte
+	var body bytes.Buffer
+	sector := 2
+	for pos, data := range chunks {
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		zw.Write(data)
+		zw.Close()
+
+		var chunk bytes.Buffer
+		binary.Write(&chunk, binary.BigEndian, uint32(compressed.Len()+1))
+		chunk.WriteByte(2)
+		chunk.Write(compressed.Bytes())
+		sectors := (chunk.Len() + regionSectorSize - 1) / regionSectorSize
+		chunk.Write(make([]byte, sectors*regionSectorSize-chunk.Len()))
+
+		idx := regionIndex(pos.X, pos.Z)
+		binary.BigEndian.PutUint32(header[idx*4:], uint32(sector<<8|sectors))
+		body.Write(chunk.Bytes())
+		sector += sectors
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	fn := path.Join(dir, fmt.Sprintf("r.%d.%d.mca", rx, rz))
+	if err := ioutil.WriteFile(fn, append(header[:], body.Bytes()...), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChunkCoverage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "region")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Chunk (-1, 0) is generated, (0, 0) is a proto-chunk still being generated
+	writeRegion(t, path.Join(dir, "region"), -1, 0, map[ChunkPos][]byte{
+		{-1, 0}: testStatusChunk(-1, 0, "minecraft:full"),
+	})
+	writeRegion(t, path.Join(dir, "region"), 0, 0, map[ChunkPos][]byte{
+		{0, 0}: testStatusChunk(0, 0, "minecraft:features"),
+	})
+
+	w := &World{Path: dir}
+	got, err := w.ChunkCoverage(context.Background(), DimensionOverworld, [3]int{-1, 1, 5}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Coverage{
+		Dimension: DimensionOverworld,
+		Box:       Box{Min: [3]int{-3, -1, 3}, Max: [3]int{1, 3, 7}},
+		Total:     2,
+		Missing:   []ChunkPos{{0, 0}},
+		Volume:    125,
+		// x in -3..-1, y in 0..1, z in 3..7
+		NonAir: 3 * 2 * 5,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCountBlocks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "region")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Chunk (0, 0) is stone below y=2, section 1 of chunk (1, 0) is all dirt
+	w := &nbtWriter{order: binary.BigEndian}
+	w.name(TagCompound, "")
+	w.name(TagInt, "DataVersion").value(int32(2975))
+	w.name(TagInt, "xPos").value(int32(1))
+	w.name(TagInt, "zPos").value(int32(0))
+	w.name(TagList, "sections").value(TagCompound).value(int32(1))
+	w.name(TagByte, "Y").value(int8(1))
+	w.name(TagCompound, "block_states")
+	w.name(TagList, "palette").value(TagCompound).value(int32(1))
+	w.name(TagString, "Name").value("minecraft:dirt").end()
+	w.end() // block_states
+	w.end() // section
+	w.end()
+	writeRegion(t, path.Join(dir, "region"), 0, 0, map[ChunkPos][]byte{
+		{0, 0}: testChunk(0, 0),
+		{1, 0}: w.Bytes(),
+	})
+
+	world := &World{Path: dir}
+	box := Box{Min: [3]int{14, 1, 0}, Max: [3]int{17, 20, 1}}
+	got, missing, err := world.CountBlocks(context.Background(), DimensionOverworld, box)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]*BlockCount{
+		// x in 14..15, y 1, z in 0..1
+		"minecraft:stone": {Count: 4, MinY: 1, MaxY: 1},
+		// x in 16..17, y in 16..20, z in 0..1
+		"minecraft:dirt": {Count: 20, MinY: 16, MaxY: 20},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if len(missing) != 0 {
+		t.Errorf("missing = %v", missing)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := world.CountBlocks(ctx, DimensionOverworld, box); err == nil {
+		t.Error("canceled count is not stopped")
+	}
+}
+
+func TestValidatePosition(t *testing.T) {
+	dir, err := ioutil.TempDir("", "region")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeRegion(t, path.Join(dir, "region"), 0, 0, map[ChunkPos][]byte{
+		{0, 0}: testChunk(0, 0),
+	})
+
+	w := &World{Path: dir, DataVersion: 2975}
+	for _, c := range []struct {
+		dim   string
+		pos   [3]float64
+		pitch float64
+		ok    bool
+	}{
+		{DimensionOverworld, [3]float64{1.5, 2, 15.9}, 10, true},
+		{DimensionOverworld, [3]float64{1.5, -64, 1}, -90, true},
+		{DimensionOverworld, [3]float64{1.5, 400, 1}, 0, false},
+		{DimensionOverworld, [3]float64{1.5, 2, 1}, 91, false},
+		{DimensionOverworld, [3]float64{16, 2, 1}, 0, false},
+		{DimensionOverworld, [3]float64{-0.5, 2, 1}, 0, false},
+		{DimensionNether, [3]float64{1, 2, 1}, 0, false},
+		{"minecraft:unknown", [3]float64{1, 2, 1}, 0, false},
+	} {
+		err := w.ValidatePosition(c.dim, c.pos, c.pitch)
+		if (err == nil) != c.ok {
+			t.Errorf("ValidatePosition(%s, %v, %v) = %v, want ok %v", c.dim, c.pos, c.pitch, err, c.ok)
+		}
+	}
+
+	// The nether keeps 0 ~ 255 after 1.18
+	for _, y := range []float64{-10, 300} {
+		err := w.ValidatePosition(DimensionNether, [3]float64{1, y, 1}, 0)
+		if err == nil || !strings.Contains(err.Error(), "not in 0 ~ 255") {
+			t.Errorf("Y %v in the nether = %v, want out of range", y, err)
+		}
+	}
+
+	w.DataVersion = 1343 // 1.12.2
+	if err := w.ValidatePosition(DimensionOverworld, [3]float64{1, -10, 1}, 0); err == nil {
+		t.Errorf("Y below 0 should be invalid before 1.18")
+	}
+}
+
+func TestUnpackLongs(t *testing.T) {
+	// 5 bits values 1..13, the 13th value spans two longs before 1.16
+	values := []uint16{}
+	for i := 1; i <= 13; i++ {
+		values = append(values, uint16(i))
+	}
+	var spanning [2]uint64
+	var packed [2]uint64
+	for i, v := range values {
+		bit := uint(i * 5)
+		spanning[bit/64] |= uint64(v) << (bit % 64)
+		if bit%64+5 > 64 {
+			spanning[bit/64+1] |= uint64(v) >> (64 - bit%64)
+		}
+		packed[i/12] |= uint64(v) << uint((i%12)*5)
+	}
+
+	for name, c := range map[string]struct {
+		longs    [2]uint64
+		spanning bool
+	}{
+		"spanning": {spanning, true},
+		"packed":   {packed, false},
+	} {
+		got, err := unpackLongs([]int64{int64(c.longs[0]), int64(c.longs[1])}, 5, 13, c.spanning)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(values, got); diff != "" {
+			t.Errorf("%s mismatch (-want +got):\n%s", name, diff)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package mc
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ResourcePack is a resource pack folder or zip
+type ResourcePack struct {
+	Name        string `json:"name"` // File or folder name
+	Path        string `json:"path"`
+	Description string `json:"description"`
+	Format      int    `json:"format"` // pack_format in pack.mcmeta
+	Icon        bool   `json:"icon"`   // Has pack.png, read by ReadResourcePackIcon
+}
+
+// ResourcePackError is a folder or zip in resource packs which can not be read
+type ResourcePackError struct {
+	Name  string `json:"name"`
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// formatCodeRegexp matches section sign formatting codes like §a
+var formatCodeRegexp = regexp.MustCompile(`§.`)
+
+// textComponent return plain text of a JSON text component,
+// which is a string, an array, or an object with text and extra
+func textComponent(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return formatCodeRegexp.ReplaceAllString(s, "")
+	}
+	var list []json.RawMessage
+	if err := json.Unmarshal(raw, &list); err == nil {
+		parts := []string{}
+		for _, item := range list {
+			parts = append(parts, textComponent(item))
+		}
+		return strings.Join(parts, "")
+	}
+	var obj struct {
+		Text      string            `json:"text"`
+		Translate string            `json:"translate"`
+		Extra     []json.RawMessage `json:"extra"`
+	}
+	if err := json.Unmarshal(raw, &obj); err == nil {
+		text := obj.Text
+		if text == "" {
+			text = obj.Translate
+		}
+		for _, item := range obj.Extra {
+			text += textComponent(item)
+		}
+		return formatCodeRegexp.ReplaceAllString(text, "")
+	}
+	return ""
+}
+
+// openResourcePack return a function reading files in a resource pack folder
+// or zip, which are not found with os.ErrNotExist. done must be called after.
+func openResourcePack(packPath string) (readFile func(name string) ([]byte, time.Time, error), done func(), err error) {
+	info, err := os.Stat(packPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.IsDir() {
+		readFile = func(name string) ([]byte, time.Time, error) {
+			fn := path.Join(packPath, name)
+			info, err := os.Stat(fn)
+			if err != nil {
+				return nil, time.Time{}, err
+			}
+			bs, err := ioutil.ReadFile(fn)
+			return bs, info.ModTime(), err
+		}
+		return readFile, func() {}, nil
+	}
+
+	zr, err := zip.OpenReader(packPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	readFile = func(name string) ([]byte, time.Time, error) {
+		for _, f := range zr.File {
+			if f.Name != name {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, time.Time{}, err
+			}
+			defer rc.Close()
+			bs, err := ioutil.ReadAll(rc)
+			return bs, info.ModTime(), err
+		}
+		return nil, time.Time{}, os.ErrNotExist
+	}
+	return readFile, func() { zr.Close() }, nil
+}
+
+// ReadResourcePack read pack.mcmeta of a resource pack folder or zip
+func ReadResourcePack(packPath string) (*ResourcePack, error) {
+	readFile, done, err := openResourcePack(packPath)
+	if err != nil {
+		return nil, fmt.Errorf("mc.ReadResourcePack: %s", err)
+	}
+	defer done()
+
+	bs, _, err := readFile("pack.mcmeta")
+	if err != nil {
+		return nil, fmt.Errorf("mc.ReadResourcePack: %s: pack.mcmeta: %s", packPath, err)
+	}
+	var meta struct {
+		Pack struct {
+			Format      int             `json:"pack_format"`
+			Description json.RawMessage `json:"description"`
+		} `json:"pack"`
+	}
+	// Some packs have BOM before JSON
+	bs = []byte(strings.TrimPrefix(string(bs), "\ufeff"))
+	if err := json.Unmarshal(bs, &meta); err != nil {
+		return nil, fmt.Errorf("mc.ReadResourcePack: %s: pack.mcmeta: %s", packPath, err)
+	}
+
+	absPath, err := filepath.Abs(packPath)
+	if err != nil {
+		return nil, fmt.Errorf("mc.ReadResourcePack: %s", err)
+	}
+	pack := &ResourcePack{
+		Name:   filepath.Base(packPath),
+		Path:   absPath,
+		Format: meta.Pack.Format,
+	}
+	if len(meta.Pack.Description) > 0 {
+		pack.Description = textComponent(meta.Pack.Description)
+	}
+	if _, _, err := readFile("pack.png"); err == nil {
+		pack.Icon = true
+	}
+	return pack, nil
+}
+
+// ReadResourcePackIcon return pack.png of a resource pack folder or zip,
+// and when it is modified
+func ReadResourcePackIcon(packPath string) ([]byte, time.Time, error) {
+	readFile, done, err := openResourcePack(packPath)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("mc.ReadResourcePackIcon: %s", err)
+	}
+	defer done()
+	bs, modTime, err := readFile("pack.png")
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("mc.ReadResourcePackIcon: %s: pack.png: %s", packPath, err)
+	}
+	return bs, modTime, nil
+}
+
+// ListResourcePacks return resource packs in dir, folders and zips
+// which are not readable packs, like ones without pack.mcmeta, are returned as errors
+func ListResourcePacks(dir string) ([]*ResourcePack, []*ResourcePackError, error) {
+	packs := []*ResourcePack{}
+	errs := []*ResourcePackError{}
+	infos, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return packs, errs, nil
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("mc.ListResourcePacks: %s", err)
+	}
+
+	for _, info := range infos {
+		if !info.IsDir() && strings.ToLower(path.Ext(info.Name())) != ".zip" {
+			continue
+		}
+		packPath := path.Join(dir, info.Name())
+		pack, err := ReadResourcePack(packPath)
+		if err != nil {
+			errs = append(errs, &ResourcePackError{Name: info.Name(), Path: packPath, Error: err.Error()})
+			continue
+		}
+		packs = append(packs, pack)
+	}
+	return packs, errs, nil
+}
@@ -0,0 +1,80 @@
+package mc
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTextComponent(t *testing.T) {
+	for raw, want := range map[string]string{
+		`"§aGreen §rpack"`:                             "Green pack",
+		`{"text": "A", "extra": ["B", {"text": "C"}]}`: "ABC",
+		`[{"text": "X"}, "Y"]`:                         "XY",
+		`42`:                                           "",
+	} {
+		if got := textComponent(json.RawMessage(raw)); got != want {
+			t.Errorf("textComponent(%s) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestListResourcePacks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "resourcepacks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	mcmeta := `{"pack": {"pack_format": 15, "description": "Folder pack"}}`
+	if err := os.MkdirAll(path.Join(dir, "Folder"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(dir, "Folder", "pack.mcmeta"), []byte(mcmeta), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	r := testArchive(t, map[string]string{
+		"pack.mcmeta": `{"pack": {"pack_format": 4, "description": {"text": "Zip pack"}}}`,
+		"pack.png":    "png",
+	})
+	bs, _ := ioutil.ReadAll(r)
+	if err := ioutil.WriteFile(path.Join(dir, "Zip.zip"), bs, 0666); err != nil {
+		t.Fatal(err)
+	}
+	// Not a pack
+	if err := ioutil.WriteFile(path.Join(dir, "notes.txt"), nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(path.Join(dir, "Empty"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	got, errs, err := ListResourcePacks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*ResourcePack{
+		{Name: "Folder", Path: path.Join(dir, "Folder"), Description: "Folder pack", Format: 15},
+		{Name: "Zip.zip", Path: path.Join(dir, "Zip.zip"), Description: "Zip pack", Format: 4, Icon: true},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if len(errs) != 1 || errs[0].Name != "Empty" || !strings.Contains(errs[0].Error, "pack.mcmeta") {
+		t.Errorf("errors = %v, want Empty without pack.mcmeta", errs)
+	}
+
+	icon, modTime, err := ReadResourcePackIcon(path.Join(dir, "Zip.zip"))
+	if err != nil || string(icon) != "png" || modTime.IsZero() {
+		t.Errorf("icon = %q, %v, %v", icon, modTime, err)
+	}
+	if _, _, err := ReadResourcePackIcon(path.Join(dir, "Folder")); err == nil {
+		t.Error("icon of pack without pack.png is found")
+	}
+}
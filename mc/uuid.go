@@ -1,93 +1,243 @@
 package mc
 
 import (
+	"crypto/md5"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 )
 
-var (
-	uuidNameMap map[string]string
-)
+// NameResolver find player name by UUID
+type NameResolver interface {
+	ResolveName(uuid string) (string, error)
+}
 
-func init() {
-	if err := readUUIDCache(); err != nil {
-		uuidNameMap = map[string]string{}
-	}
+// ErrNameNotFound returned when a resolver does not know the UUID
+var ErrNameNotFound = errors.New("Player name not found")
+
+var nameResolver NameResolver = &ChainResolver{}
+
+// SetNameResolver set the resolver used when reading players of worlds
+func SetNameResolver(r NameResolver) {
+	nameResolver = r
 }
 
-func readUUIDCache() error {
-	bs, err := ioutil.ReadFile("usercache.json")
+func uuidToName(uuid string) (string, error) {
+	name, err := nameResolver.ResolveName(uuid)
 	if err != nil {
-		return fmt.Errorf("app.main.readUUIDCache: %s", err)
+		return "", fmt.Errorf("mc.uuidToName: %s: %s", uuid, err)
+	}
+	return name, nil
+}
+
+// ChainResolver tries resolvers in order,
+// names found are stored into Cache if it is set
+type ChainResolver struct {
+	Resolvers []NameResolver
+	Cache     *CacheResolver
+}
+
+// ResolveName implements NameResolver
+func (r *ChainResolver) ResolveName(uuid string) (string, error) {
+	errs := []string{}
+	for _, resolver := range r.Resolvers {
+		name, err := resolver.ResolveName(uuid)
+		if err == ErrNameNotFound {
+			continue
+		} else if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if r.Cache != nil && resolver != NameResolver(r.Cache) {
+			if err := r.Cache.Store(uuid, name); err != nil {
+				log.Println(err)
+			}
+		}
+		return name, nil
+	}
+	if len(errs) > 0 {
+		return "", fmt.Errorf("%s (%s)", ErrNameNotFound, strings.Join(errs, "; "))
+	}
+	return "", ErrNameNotFound
+}
+
+// UserCacheResolver reads usercache.json written by minecraft client or server
+type UserCacheResolver struct {
+	Files []string
+}
+
+// ResolveName implements NameResolver, files are read on every call
+// since the game keeps updating them
+func (r *UserCacheResolver) ResolveName(uuid string) (string, error) {
+	for _, fn := range r.Files {
+		bs, err := ioutil.ReadFile(fn)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return "", fmt.Errorf("mc.UserCacheResolver: %s", err)
+		}
+		var entries []struct {
+			Name string `json:"name"`
+			UUID string `json:"uuid"`
+		}
+		if err := json.Unmarshal(bs, &entries); err != nil {
+			return "", fmt.Errorf("mc.UserCacheResolver: %s: %s", fn, err)
+		}
+		for _, entry := range entries {
+			if strings.EqualFold(entry.UUID, uuid) {
+				return entry.Name, nil
+			}
+		}
+	}
+	return "", ErrNameNotFound
+}
+
+// OfflineResolver matches offline-mode UUIDs, which are derived from names,
+// against known names
+type OfflineResolver struct {
+	Names []string
+}
+
+// ResolveName implements NameResolver
+func (r *OfflineResolver) ResolveName(uuid string) (string, error) {
+	// Version 3 UUID
+	if len(uuid) != 36 || uuid[14] != '3' {
+		return "", ErrNameNotFound
+	}
+	for _, name := range r.Names {
+		if strings.EqualFold(OfflineUUID(name), uuid) {
+			return name, nil
+		}
+	}
+	return "", ErrNameNotFound
+}
+
+// OfflineUUID return UUID of player name in offline-mode server,
+// which is UUID v3 of "OfflinePlayer:<name>" without namespace
+func OfflineUUID(name string) string {
+	sum := md5.Sum([]byte("OfflinePlayer:" + name))
+	sum[6] = sum[6]&0x0f | 0x30
+	sum[8] = sum[8]&0x3f | 0x80
+	s := fmt.Sprintf("%x", sum)
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:32]
+}
+
+// CacheResolver stores resolved names in a JSON file
+type CacheResolver struct {
+	mutex    sync.Mutex
+	filename string
+	names    map[string]string
+}
+
+// NewCacheResolver return a cache stored in filename,
+// the file is created when the first name is stored
+func NewCacheResolver(filename string) (*CacheResolver, error) {
+	r := &CacheResolver{filename: filename, names: map[string]string{}}
+	bs, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return r, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("mc.NewCacheResolver: %s", err)
 	}
-	err = json.Unmarshal(bs, &uuidNameMap)
+	err = json.Unmarshal(bs, &r.names)
 	if err != nil {
-		return fmt.Errorf("app.main.readUUIDCache: %s", err)
+		return nil, fmt.Errorf("mc.NewCacheResolver: %s: %s", filename, err)
 	}
-	return nil
+	return r, nil
 }
 
-func writeUUIDCache() error {
-	bs, err := json.Marshal(uuidNameMap)
+// ResolveName implements NameResolver
+func (r *CacheResolver) ResolveName(uuid string) (string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if name, exist := r.names[uuid]; exist {
+		return name, nil
+	}
+	return "", ErrNameNotFound
+}
+
+// Store save name of uuid
+func (r *CacheResolver) Store(uuid, name string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.names[uuid] == name {
+		return nil
+	}
+	r.names[uuid] = name
+
+	bs, err := json.Marshal(r.names)
+	if err != nil {
+		return fmt.Errorf("mc.CacheResolver.Store: %s", err)
+	}
+	err = os.MkdirAll(filepath.Dir(r.filename), os.ModePerm)
 	if err != nil {
-		return fmt.Errorf("app.main.writeUUIDCache: %s", err)
+		return fmt.Errorf("mc.CacheResolver.Store: %s", err)
 	}
-	err = ioutil.WriteFile("usercache.json", bs, 0666)
+	err = ioutil.WriteFile(r.filename, bs, 0666)
 	if err != nil {
-		return fmt.Errorf("app.main.writeUUIDCache: %s", err)
+		return fmt.Errorf("mc.CacheResolver.Store: %s", err)
 	}
 	return nil
 }
 
-func uuidToName(uuid string) (string, error) {
-	if name, exist := uuidNameMap[uuid]; exist {
-		return name, nil
-	}
+// DefaultProfileURL is Mojang's session server profile API
+const DefaultProfileURL = "https://sessionserver.mojang.com/session/minecraft/profile"
 
-	url := fmt.Sprintf("https://api.mojang.com/user/profiles/%s/names",
-		strings.Replace(uuid, "-", "", -1))
-	client := http.Client{}
+// HTTPResolver looks up profiles by GET BaseURL/<uuid without dashes>,
+// the response is a profile like {"name": "..."}
+type HTTPResolver struct {
+	BaseURL string
+	Timeout time.Duration
+}
+
+// ResolveName implements NameResolver
+func (r *HTTPResolver) ResolveName(uuid string) (string, error) {
+	baseURL := r.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultProfileURL
+	}
+	url := strings.TrimRight(baseURL, "/") + "/" + strings.Replace(uuid, "-", "", -1)
+	client := http.Client{Timeout: r.Timeout}
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
-		return "", fmt.Errorf("app.uuidToName: %s", err)
+		return "", fmt.Errorf("mc.HTTPResolver: %s", err)
 	}
 	req.Header.Set("User-Agent", "Get Name By UUID")
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("app.uuidToName: %s", err)
+		return "", fmt.Errorf("mc.HTTPResolver: %s", err)
 	}
 	defer resp.Body.Close()
 	bytes, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("app.uuidToName: %s", err)
+		return "", fmt.Errorf("mc.HTTPResolver: %s", err)
 	}
-	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("app.uuidToName: Request StatusCode = %d, %s",
+	if resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotFound {
+		return "", ErrNameNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("mc.HTTPResolver: Request StatusCode = %d, %s",
 			resp.StatusCode, string(bytes))
 	}
 
-	var tmp []struct {
+	var profile struct {
 		Name string `json:"name"`
 	}
-	err = json.Unmarshal(bytes, &tmp)
+	err = json.Unmarshal(bytes, &profile)
 	if err != nil {
-		return "", fmt.Errorf("app.uuidToName: %s", err)
-	}
-	if len(tmp) == 0 {
-		return "", fmt.Errorf("app.uuidToName: cannot found player by uuid %s", uuid)
+		return "", fmt.Errorf("mc.HTTPResolver: %s", err)
 	}
-
-	uuidNameMap[uuid] = tmp[0].Name
-
-	err = writeUUIDCache()
-	if err != nil {
-		log.Println(err)
+	if profile.Name == "" {
+		return "", ErrNameNotFound
 	}
-
-	return tmp[0].Name, nil
+	return profile.Name, nil
 }
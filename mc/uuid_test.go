@@ -0,0 +1,80 @@
+package mc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestOfflineUUID(t *testing.T) {
+	if got := OfflineUUID("Notch"); got != "b50ad385-829d-3141-a216-7e7d7539ba7f" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestChainResolver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "uuid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	userCache := path.Join(dir, "usercache.json")
+	err = ioutil.WriteFile(userCache,
+		[]byte(`[{"name":"Alex","uuid":"00000000-0000-4000-8000-000000000001","expiresOn":""}]`), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/profile/00000000000040008000000000000002" {
+			fmt.Fprint(w, `{"id":"00000000000040008000000000000002","name":"Steve"}`)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	cacheFile := path.Join(dir, "data", "cache.json")
+	cache, err := NewCacheResolver(cacheFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := &ChainResolver{
+		Resolvers: []NameResolver{
+			&UserCacheResolver{Files: []string{path.Join(dir, "missing.json"), userCache}},
+			&OfflineResolver{Names: []string{"Notch"}},
+			cache,
+			&HTTPResolver{BaseURL: srv.URL + "/profile", Timeout: time.Second},
+		},
+		Cache: cache,
+	}
+
+	for uuid, want := range map[string]string{
+		"00000000-0000-4000-8000-000000000001": "Alex",
+		"b50ad385-829d-3141-a216-7e7d7539ba7f": "Notch",
+		"00000000-0000-4000-8000-000000000002": "Steve",
+	} {
+		got, err := chain.ResolveName(uuid)
+		if err != nil || got != want {
+			t.Errorf("%s: got %s, %v, want %s", uuid, got, err, want)
+		}
+	}
+	if _, err := chain.ResolveName("00000000-0000-4000-8000-000000000003"); err != ErrNameNotFound {
+		t.Errorf("unknown UUID should not be found, got %v", err)
+	}
+
+	// Names are cached and served without other resolvers
+	cache, err = NewCacheResolver(cacheFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := cache.ResolveName("00000000-0000-4000-8000-000000000002"); err != nil || got != "Steve" {
+		t.Errorf("cache: got %s, %v", got, err)
+	}
+}
@@ -0,0 +1,138 @@
+package mc
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ClientVersion is a version installed in versions folder of minecraft client
+type ClientVersion struct {
+	ID           string `json:"id"`   // Folder name, like 1.20.1 or fabric-loader-0.14.21-1.20.1
+	Jar          string `json:"jar"`  // Client jar, of the inherited version for modded ones
+	Type         string `json:"type"` // release, snapshot, old_beta...
+	ReleaseTime  string `json:"release_time"`
+	InheritsFrom string `json:"inherits_from,omitempty"`
+}
+
+// ListClientVersions return versions having a client jar in mcDir/versions,
+// newest released first
+func ListClientVersions(mcDir string) ([]*ClientVersion, error) {
+	versionsDir := path.Join(mcDir, "versions")
+	infos, err := ioutil.ReadDir(versionsDir)
+	if os.IsNotExist(err) {
+		return []*ClientVersion{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("mc.ListClientVersions: %s", err)
+	}
+
+	all := map[string]*ClientVersion{}
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+		id := info.Name()
+		bs, err := ioutil.ReadFile(path.Join(versionsDir, id, id+".json"))
+		if err != nil {
+			continue
+		}
+		var meta struct {
+			Type         string `json:"type"`
+			ReleaseTime  string `json:"releaseTime"`
+			InheritsFrom string `json:"inheritsFrom"`
+		}
+		if err := json.Unmarshal(bs, &meta); err != nil {
+			continue
+		}
+		v := &ClientVersion{
+			ID:           id,
+			Type:         meta.Type,
+			ReleaseTime:  meta.ReleaseTime,
+			InheritsFrom: meta.InheritsFrom,
+		}
+		if jar := path.Join(versionsDir, id, id+".jar"); isFile(jar) {
+			v.Jar = jar
+		}
+		all[id] = v
+	}
+
+	ret := []*ClientVersion{}
+	for _, v := range all {
+		// Modded versions use jar of the version they inherit
+		for p, depth := v, 0; v.Jar == "" && p != nil && depth < 8; depth++ {
+			p = all[p.InheritsFrom]
+			if p != nil && p.Jar != "" {
+				v.Jar = p.Jar
+			}
+		}
+		if v.Jar != "" {
+			ret = append(ret, v)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].ReleaseTime != ret[j].ReleaseTime {
+			return ret[i].ReleaseTime > ret[j].ReleaseTime
+		}
+		return ret[i].ID < ret[j].ID
+	})
+	return ret, nil
+}
+
+// assetPrefixes are folders in client jar used to render blocks
+var assetPrefixes = []string{
+	"assets/minecraft/textures/",
+	"assets/minecraft/blockstates/",
+	"assets/minecraft/models/",
+}
+
+// ExtractAssets unpack textures, blockstates and models of client jar into dir,
+// dir is laid out as a resource pack. Files are unpacked to a temp folder first,
+// so dir is complete once it exists.
+func ExtractAssets(jar, dir string) error {
+	zr, err := zip.OpenReader(jar)
+	if err != nil {
+		return fmt.Errorf("mc.ExtractAssets: %s", err)
+	}
+	defer zr.Close()
+
+	if err := os.MkdirAll(path.Dir(dir), os.ModePerm); err != nil {
+		return fmt.Errorf("mc.ExtractAssets: %s", err)
+	}
+	tmp, err := ioutil.TempDir(path.Dir(dir), ".extract-")
+	if err != nil {
+		return fmt.Errorf("mc.ExtractAssets: %s", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	err = extractZip(&zr.Reader, tmp, func(name string) bool {
+		for _, prefix := range assetPrefixes {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return fmt.Errorf("mc.ExtractAssets: %s: %s", jar, err)
+	}
+	if !isDir(path.Join(tmp, "assets", "minecraft", "textures")) {
+		return fmt.Errorf("mc.ExtractAssets: %s has no textures", jar)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("mc.ExtractAssets: %s", err)
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return fmt.Errorf("mc.ExtractAssets: %s", err)
+	}
+	return nil
+}
+
+func isFile(name string) bool {
+	info, err := os.Stat(name)
+	return err == nil && info.Mode().IsRegular()
+}
@@ -0,0 +1,115 @@
+package mc
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestClientVersions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	jar := testArchive(t, map[string]string{
+		"assets/minecraft/textures/block/stone.png":     "stone",
+		"assets/minecraft/blockstates/stone.json":       "{}",
+		"assets/minecraft/models/block/stone.json":      "{}",
+		"assets/minecraft/lang/en_us.json":              "{}",
+		"net/minecraft/client/Main.class":               "",
+		"data/minecraft/recipes/stone_slab.json":        "{}",
+		"assets/minecraft/textures/../../../../escaped": "",
+	})
+	bs, _ := ioutil.ReadAll(jar)
+
+	versions := path.Join(dir, "versions")
+	for id, meta := range map[string]string{
+		"1.20.1":        `{"type": "release", "releaseTime": "2023-06-12T13:25:51+00:00"}`,
+		"fabric-1.20.1": `{"inheritsFrom": "1.20.1", "releaseTime": "2023-06-12T13:25:51+00:00"}`,
+		"1.19":          `{"type": "release", "releaseTime": "2022-06-07T09:42:18+00:00"}`,
+	} {
+		if err := os.MkdirAll(path.Join(versions, id), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path.Join(versions, id, id+".json"), []byte(meta), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 1.19 is not downloaded yet
+	if err := ioutil.WriteFile(path.Join(versions, "1.20.1", "1.20.1.jar"), bs, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ListClientVersions(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jarPath := path.Join(versions, "1.20.1", "1.20.1.jar")
+	want := []*ClientVersion{
+		{ID: "1.20.1", Jar: jarPath, Type: "release", ReleaseTime: "2023-06-12T13:25:51+00:00"},
+		{ID: "fabric-1.20.1", Jar: jarPath, ReleaseTime: "2023-06-12T13:25:51+00:00", InheritsFrom: "1.20.1"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("versions mismatch (-want +got):\n%s", diff)
+	}
+
+	// Entry escaping assets is rejected
+	assets := path.Join(dir, "assets", "1.20.1")
+	if err := ExtractAssets(jarPath, assets); err == nil {
+		t.Errorf("jar with bad entry should be rejected")
+	}
+	if _, err := os.Stat(assets); !os.IsNotExist(err) {
+		t.Errorf("assets of failed extraction exist")
+	}
+}
+
+func TestExtractAssets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "assets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	jar := testArchive(t, map[string]string{
+		"assets/minecraft/textures/block/stone.png": "stone",
+		"assets/minecraft/blockstates/stone.json":   "{}",
+		"assets/minecraft/models/block/stone.json":  "{}",
+		"assets/minecraft/lang/en_us.json":          "{}",
+		"net/minecraft/client/Main.class":           "",
+	})
+	bs, _ := ioutil.ReadAll(jar)
+	jarPath := path.Join(dir, "client.jar")
+	if err := ioutil.WriteFile(jarPath, bs, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	assets := path.Join(dir, "assets", "1.20.1")
+	if err := ExtractAssets(jarPath, assets); err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, fn := range []string{
+		"assets/minecraft/textures/block/stone.png",
+		"assets/minecraft/blockstates/stone.json",
+		"assets/minecraft/models/block/stone.json",
+		"assets/minecraft/lang/en_us.json",
+		"net/minecraft/client/Main.class",
+	} {
+		if isFile(path.Join(assets, fn)) {
+			got = append(got, fn)
+		}
+	}
+	want := []string{
+		"assets/minecraft/textures/block/stone.png",
+		"assets/minecraft/blockstates/stone.json",
+		"assets/minecraft/models/block/stone.json",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("extracted mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -1,68 +1,156 @@
 package mc
 
 import (
-	"encoding/base64"
 	"fmt"
 	"io/ioutil"
-	"log"
+	"math"
+	"os"
 	"path"
 	"path/filepath"
 )
 
 // World stores data of minecraft world
 type World struct {
-	Name    string   `json:"name"`
-	Path    string   `json:"path"`
-	Icon    string   `json:"icon"`
-	Players []string `json:"players"`
+	Name    string    `json:"name"`   // LevelName in level.dat, folder name if not set
+	Folder  string    `json:"folder"` // Folder name
+	Path    string    `json:"path"`
+	Icon    string    `json:"icon"` // Icon file in world folder, "" if none
+	Players []*Player `json:"players"`
+
+	Dimensions []Dimension `json:"dimensions"`
+
+	Edition string `json:"edition"` // java or bedrock
+	// Renderable tells whether mc2pbrt can read the world, Bedrock ones can not
+	Renderable bool `json:"renderable"`
+	// Convertible Bedrock world has a complete database,
+	// which converters like Chunker can turn into a Java world
+	Convertible bool `json:"convertible"`
+
+	// Version is game version name like "1.14.4", "" before 1.9
+	Version     string `json:"version"`
+	DataVersion int    `json:"data_version"`
+
+	Spawn    [3]int `json:"spawn"` // X, Y, Z
+	GameMode string `json:"game_mode"`
+	Hardcore bool   `json:"hardcore"`
+
+	LastPlayed int64 `json:"last_played"` // Unix time in milliseconds
+	DayTime    int64 `json:"day_time"`    // Ticks, 24000 per day
+	Raining    bool  `json:"raining"`
+	Thundering bool  `json:"thundering"`
 }
 
+// gameModes maps GameType in level.dat to name
+var gameModes = []string{"survival", "creative", "adventure", "spectator"}
+
 // NewWorld return new minecraft world info
 func NewWorld(dir string) (*World, error) {
-	players, err := listUsers(dir)
-	if err != nil {
+	// Worlds never saved by the game have no level.dat
+	levelDat := Compound{}
+	levelDatPath := path.Join(dir, "level.dat")
+	if bs, err := ioutil.ReadFile(levelDatPath); err == nil {
+		if isBedrockLevelDat(bs) {
+			return newBedrockWorld(dir, bs)
+		}
+		levelDat, err = DecodeNBT(bs)
+		if err != nil {
+			return nil, fmt.Errorf("app.main.NewWorld: %s: %s", levelDatPath, err)
+		}
+	} else if !os.IsNotExist(err) {
 		return nil, fmt.Errorf("app.main.NewWorld: %s", err)
 	}
 
-	iconBase64 := ""
-	bytes, err := ioutil.ReadFile(path.Join(dir, "icon.png"))
+	players, err := listPlayers(dir, levelDat.Compound("Data"))
 	if err != nil {
-		iconBase64 = ""
+		return nil, fmt.Errorf("app.main.NewWorld: %s", err)
 	}
-	iconBase64 = base64.StdEncoding.EncodeToString(bytes)
 
 	absDir, err := filepath.Abs(dir)
 	if err != nil {
 		return nil, fmt.Errorf("app.main.NewWorld: %s", err)
 	}
 
-	return &World{
-		Players: players,
-		Name:    filepath.Base(dir),
-		Icon:    iconBase64,
-		Path:    absDir,
-	}, nil
+	dims, err := listDimensions(absDir)
+	if err != nil {
+		return nil, fmt.Errorf("app.main.NewWorld: %s", err)
+	}
+
+	world := &World{
+		Players:    players,
+		Dimensions: dims,
+		Name:       filepath.Base(dir),
+		Folder:     filepath.Base(dir),
+		Icon:       findIcon(dir, "icon.png"),
+		Path:       absDir,
+		Edition:    EditionJava,
+		Renderable: true,
+	}
+	world.readLevelData(levelDat.Compound("Data"))
+	return world, nil
 }
 
-func listUsers(mcDir string) ([]string, error) {
-	files, err := ioutil.ReadDir(path.Join(mcDir, "playerdata"))
-	if err != nil {
-		return nil, fmt.Errorf("app.listUsers: %s", err)
+// findIcon return name if file name is in dir, "" otherwise
+func findIcon(dir, name string) string {
+	if !isFile(path.Join(dir, name)) {
+		return ""
 	}
+	return name
+}
 
-	users := []string{}
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
-		fn := file.Name()
-		playerName, err := uuidToName(fn[0 : len(fn)-4])
-		if err != nil {
-			log.Println(err)
-			continue
-		}
-		users = append(users, playerName)
+// IconPath return path of icon file of world, "" if world has no icon
+func (w *World) IconPath() string {
+	if w.Icon == "" {
+		return ""
+	}
+	return path.Join(w.Path, w.Icon)
+}
+
+func (w *World) readLevelData(data Compound) {
+	if name := data.String("LevelName"); name != "" {
+		w.Name = name
+	}
+	w.Version = data.Compound("Version").String("Name")
+	w.DataVersion = int(data.Int("DataVersion"))
+
+	w.Spawn = [3]int{
+		int(data.Int("SpawnX")),
+		int(data.Int("SpawnY")),
+		int(data.Int("SpawnZ")),
+	}
+	if gameType := data.Int("GameType"); gameType >= 0 && int(gameType) < len(gameModes) {
+		w.GameMode = gameModes[gameType]
 	}
+	w.Hardcore = data.Bool("hardcore")
 
-	return users, nil
+	w.LastPlayed = data.Int("LastPlayed")
+	w.DayTime = data.Int("DayTime")
+	w.Raining = data.Bool("raining")
+	w.Thundering = data.Bool("thundering")
+}
+
+// TicksPerDay is length of a day in game ticks
+const TicksPerDay = 24000
+
+// DayHour return hour of the clock at dayTime in 0 ~ 24, tick 0 is 6:00
+func DayHour(dayTime int64) float64 {
+	ticks := dayTime % TicksPerDay
+	if ticks < 0 {
+		ticks += TicksPerDay
+	}
+	return math.Mod(float64(ticks)/1000+6, 24)
+}
+
+// CelestialAngle return angle of sun at dayTime as fraction of a turn from noon,
+// 0.25 is sunset, the same as the game draws the sky
+func CelestialAngle(dayTime int64) float64 {
+	ticks := dayTime % TicksPerDay
+	if ticks < 0 {
+		ticks += TicksPerDay
+	}
+	d := float64(ticks)/TicksPerDay - 0.25
+	if d < 0 {
+		d++
+	}
+	e := 0.5 - math.Cos(d*math.Pi)/2
+	return (2*d + e) / 3
 }
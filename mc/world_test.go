@@ -0,0 +1,29 @@
+package mc
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDayTime(t *testing.T) {
+	for _, c := range []struct {
+		dayTime int64
+		hour    float64
+		angle   float64
+	}{
+		// The game draws the sun above horizon at tick 0
+		{0, 6, 0.7845177968644247},
+		{6000, 12, 0},
+		{12000, 18, 0.21548220313557542},
+		{18000, 0, 0.5},
+		{24000 * 3, 6, 0.7845177968644247},
+		{-6000, 0, 0.5},
+	} {
+		if got := DayHour(c.dayTime); math.Abs(got-c.hour) > 1e-9 {
+			t.Errorf("DayHour(%d) = %v, want %v", c.dayTime, got, c.hour)
+		}
+		if got := CelestialAngle(c.dayTime); math.Abs(got-c.angle) > 1e-9 {
+			t.Errorf("CelestialAngle(%d) = %v, want %v", c.dayTime, got, c.angle)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package mcwdrv
+
+// Compatibility of a world with mc2pbrt
+const (
+	// CompatCompatible -> DataVersion of world is in the tested range
+	CompatCompatible = "compatible"
+
+	// CompatUntested -> world may render, like newer than tested or no DataVersion
+	CompatUntested = "untested"
+
+	// CompatUnsupported -> mc2pbrt can not read the world
+	CompatUnsupported = "unsupported"
+)
+
+// DataVersionRange is DataVersion of worlds mc2pbrt can read,
+// zero fields are not checked
+type DataVersionRange struct {
+	Min    int `yaml:"min" json:"min"`       // Oldest supported
+	Tested int `yaml:"tested" json:"tested"` // Newest tested, newer ones are untested
+	Max    int `yaml:"max" json:"max"`       // Newest supported
+}
+
+// Compatibility return compatibility of world saved with dataVersion,
+// 0 for worlds before DataVersion was added, which are older than any Min
+func (r DataVersionRange) Compatibility(dataVersion int) string {
+	switch {
+	case r.Min > 0 && dataVersion < r.Min:
+		return CompatUnsupported
+	case dataVersion == 0:
+		return CompatUntested
+	case r.Max > 0 && dataVersion > r.Max:
+		return CompatUnsupported
+	case r.Tested > 0 && dataVersion <= r.Tested:
+		return CompatCompatible
+	}
+	return CompatUntested
+}
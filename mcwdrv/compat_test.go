@@ -0,0 +1,29 @@
+package mcwdrv
+
+import "testing"
+
+func TestCompatibility(t *testing.T) {
+	r := DataVersionRange{Min: 1139, Tested: 2586, Max: 3465}
+	for dataVersion, want := range map[int]string{
+		0:    CompatUnsupported,
+		1000: CompatUnsupported,
+		1139: CompatCompatible,
+		2586: CompatCompatible,
+		3000: CompatUntested,
+		3465: CompatUntested,
+		3700: CompatUnsupported,
+	} {
+		if got := r.Compatibility(dataVersion); got != want {
+			t.Errorf("Compatibility(%d) = %s, want %s", dataVersion, got, want)
+		}
+	}
+
+	// Nothing is known without range
+	if got := (DataVersionRange{}).Compatibility(3700); got != CompatUntested {
+		t.Errorf("Compatibility without range = %s, want %s", got, CompatUntested)
+	}
+	// Worlds without DataVersion are untested if Min is not set
+	if got := (DataVersionRange{Tested: 2586}).Compatibility(0); got != CompatUntested {
+		t.Errorf("Compatibility(0) without min = %s, want %s", got, CompatUntested)
+	}
+}
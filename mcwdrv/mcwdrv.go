@@ -26,6 +26,9 @@ const (
 	// StatusReady -> MCW is config to ready for compiling
 	StatusReady
 
+	// StatusSnapshot -> MCW is copying the world
+	StatusSnapshot
+
 	// StatusMc2pbrt -> MCW is running mc2pbrt
 	StatusMc2pbrt
 
@@ -48,6 +51,7 @@ type MCWDriver struct {
 		mc2pbrtMain string
 		logDir      string
 	}
+	disableSnapshot bool
 
 	pbrtDrv *pbrtDrv
 }
@@ -58,11 +62,20 @@ type Class struct {
 	Params interface{} `json:"params"`
 }
 
+// Target is a position to render at instead of a player,
+// Yaw and Pitch are in degrees as Minecraft
+type Target struct {
+	X, Y, Z    float64
+	Yaw, Pitch float64
+	Dimension  string
+}
+
 // RenderConfig is Minecraft scene render config for mc2pbrt
 // more info ref: https://github.com/PbrtCraft/mc2pbrt
 type RenderConfig struct {
 	World      string
 	Player     string
+	Target     *Target `json:",omitempty"` // Used instead of Player if not nil
 	Sample     int
 	Radius     int
 	Resolution struct {
@@ -73,6 +86,12 @@ type RenderConfig struct {
 	Method      Class
 	Camera      Class
 	Phenomenons []Class
+
+	// Paths of resource pack folders or zips, the first one has highest priority
+	ResourcePacks []string `json:",omitempty"`
+	// Folder of textures, blockstates and models extracted from a client jar,
+	// laid out as a resource pack
+	Assets string `json:",omitempty"`
 }
 
 // ErrDriverNotIdel occur when try to compile when compiling
@@ -84,6 +103,12 @@ type Config struct {
 	Mc2pbrtMain string `yaml:"mc2pbrt_main"` // Path to mc2pbrt/main.py
 	PbrtBin     string `yaml:"pbrt_bin"`     // Path to pbrt binary
 	LogDir      string `yaml:"log_dir"`      // mcwdrv log directory
+
+	// mc2pbrt reads the world directly instead of a snapshot in workdir
+	DisableSnapshot bool `yaml:"disable_snapshot"`
+
+	// DataVersion of worlds the installed mc2pbrt can read
+	DataVersion DataVersionRange `yaml:"data_version"`
 }
 
 // NewMCWDriver return a minecraft world driver
@@ -115,6 +140,8 @@ func NewMCWDriver(conf *Config) (*MCWDriver, error) {
 		return nil, fmt.Errorf("mcwdrv.NewMCWDriver: %s", err)
 	}
 
+	ret.disableSnapshot = conf.DisableSnapshot
+
 	ret.pbrtDrv = &pbrtDrv{
 		workdir: ret.path.workdir,
 		bin:     pbrtBin,
@@ -123,25 +150,21 @@ func NewMCWDriver(conf *Config) (*MCWDriver, error) {
 	return ret, nil
 }
 
-// Compile start a goroutine to generate pbrt file and render
+// Compile start a goroutine to snapshot the world, generate pbrt file and render
 func (drv *MCWDriver) Compile(rc RenderConfig) error {
-	err := drv.writeRenderConfig(rc)
-	if err != nil {
-		return fmt.Errorf("mcwdrv.Compile: %s", err)
-	}
-
 	drv.mutex.Lock()
 	if drv.status != StatusIdle {
+		drv.mutex.Unlock()
 		return ErrDriverNotIdel
 	}
 	drv.status = StatusReady
 	drv.mutex.Unlock()
 
-	go drv.compile()
+	go drv.compile(rc)
 	return nil
 }
 
-func (drv *MCWDriver) compile() {
+func (drv *MCWDriver) compile(rc RenderConfig) {
 	defer func() {
 		drv.setStatus(StatusIdle)
 	}()
@@ -157,6 +180,25 @@ func (drv *MCWDriver) compile() {
 	}
 	defer logFile.Close()
 
+	if !drv.disableSnapshot {
+		log.Println("Start taking snapshot...")
+		drv.setStatus(StatusSnapshot)
+		rc.World, err = drv.snapshot(rc)
+		if err != nil {
+			log.Printf("snapshot: %s", err)
+			fmt.Fprintln(logFile, err)
+			drv.lastCompile.err = fmt.Errorf("snapshot: %s", err)
+			return
+		}
+		log.Println("Start taking snapshot...ok")
+	}
+
+	err = drv.writeRenderConfig(rc)
+	if err != nil {
+		drv.lastCompile.err = err
+		return
+	}
+
 	log.Println("Start running mc2pbrt...")
 	drv.setStatus(StatusMc2pbrt)
 	mc2pbrtMain := drv.path.mc2pbrtMain
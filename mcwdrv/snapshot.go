@@ -0,0 +1,209 @@
+package mcwdrv
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/PbrtCraft/pbrtcraftdrv/mc"
+)
+
+// Snapshot is retried when the world is saved while copying
+const (
+	snapshotRetries    = 3
+	snapshotRetryDelay = 2 * time.Second
+)
+
+// fileStamp is used to find files changed while copying
+type fileStamp struct {
+	exists  bool
+	modTime time.Time
+	size    int64
+}
+
+func stampFile(filename string) (fileStamp, error) {
+	info, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return fileStamp{}, nil
+	} else if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{true, info.ModTime(), info.Size()}, nil
+}
+
+func stampFiles(dir string, files []string) (map[string]fileStamp, error) {
+	ret := map[string]fileStamp{}
+	for _, fn := range files {
+		stamp, err := stampFile(path.Join(dir, fn))
+		if err != nil {
+			return nil, err
+		}
+		ret[fn] = stamp
+	}
+	return ret, nil
+}
+
+// renderCenter return dimension and block position to render at
+func renderCenter(rc RenderConfig) (string, [3]int, error) {
+	var center [3]int
+	if rc.Target != nil {
+		for i, v := range []float64{rc.Target.X, rc.Target.Y, rc.Target.Z} {
+			center[i] = int(math.Floor(v))
+		}
+		return rc.Target.Dimension, center, nil
+	}
+
+	player, err := mc.FindPlayer(rc.World, rc.Player)
+	if err != nil {
+		return "", center, fmt.Errorf("mcwdrv.renderCenter: %s", err)
+	}
+	for i := 0; i < 3; i++ {
+		center[i] = int(math.Floor(player.Pos[i]))
+	}
+	return player.Dimension, center, nil
+}
+
+// snapshotFiles return files of world needed to render box, relative to world
+func snapshotFiles(worldPath, dim string, box mc.Box) ([]string, error) {
+	files := []string{"level.dat"}
+
+	players, err := ioutil.ReadDir(path.Join(worldPath, "playerdata"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("mcwdrv.snapshotFiles: %s", err)
+	}
+	for _, player := range players {
+		if !player.IsDir() && path.Ext(player.Name()) == ".dat" {
+			files = append(files, path.Join("playerdata", player.Name()))
+		}
+	}
+
+	dimDir, err := mc.DimensionDir(worldPath, dim)
+	if err != nil {
+		return nil, fmt.Errorf("mcwdrv.snapshotFiles: %s", err)
+	}
+	regionDir, err := filepath.Rel(worldPath, path.Join(dimDir, "region"))
+	if err != nil {
+		return nil, fmt.Errorf("mcwdrv.snapshotFiles: %s", err)
+	}
+	regionDir = filepath.ToSlash(regionDir)
+
+	regions := map[string]bool{}
+	for _, pos := range box.Chunks() {
+		fn := mc.RegionFilename(regionDir, pos.X, pos.Z)
+		if !regions[fn] {
+			regions[fn] = true
+			files = append(files, fn)
+		}
+	}
+
+	// Oversized chunks of the regions are stored in c.<x>.<z>.mcc
+	infos, err := ioutil.ReadDir(path.Join(worldPath, regionDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("mcwdrv.snapshotFiles: %s", err)
+	}
+	for _, info := range infos {
+		var cx, cz int
+		if _, err := fmt.Sscanf(info.Name(), "c.%d.%d.mcc", &cx, &cz); err != nil {
+			continue
+		}
+		if regions[mc.RegionFilename(regionDir, cx, cz)] {
+			files = append(files, path.Join(regionDir, info.Name()))
+		}
+	}
+	return files, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(path.Dir(dst), os.ModePerm); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// takeSnapshot copy level.dat, playerdata and region files covering box into dir.
+// Files and session.lock are checked before and after copying, the copy is
+// retried if the world is saved or reopened meanwhile.
+func takeSnapshot(worldPath, dir, dim string, box mc.Box) error {
+	files, err := snapshotFiles(worldPath, dim, box)
+	if err != nil {
+		return fmt.Errorf("mcwdrv.takeSnapshot: %s", err)
+	}
+	checked := append([]string{"session.lock"}, files...)
+
+	for attempt := 0; attempt < snapshotRetries; attempt++ {
+		if attempt > 0 {
+			log.Println("mcwdrv.takeSnapshot: world changed while copying, retry")
+			time.Sleep(snapshotRetryDelay)
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("mcwdrv.takeSnapshot: %s", err)
+		}
+
+		before, err := stampFiles(worldPath, checked)
+		if err != nil {
+			return fmt.Errorf("mcwdrv.takeSnapshot: %s", err)
+		}
+		for _, fn := range files {
+			if !before[fn].exists {
+				continue
+			}
+			if err := copyFile(path.Join(worldPath, fn), path.Join(dir, fn)); err != nil {
+				return fmt.Errorf("mcwdrv.takeSnapshot: %s", err)
+			}
+		}
+		after, err := stampFiles(worldPath, checked)
+		if err != nil {
+			return fmt.Errorf("mcwdrv.takeSnapshot: %s", err)
+		}
+
+		consistent := true
+		for _, fn := range checked {
+			if before[fn] != after[fn] {
+				consistent = false
+				break
+			}
+		}
+		if consistent {
+			return nil
+		}
+	}
+	return fmt.Errorf("mcwdrv.takeSnapshot: world keeps changing after %d tries", snapshotRetries)
+}
+
+// snapshot take snapshot of world to render in workdir, return path of snapshot
+func (drv *MCWDriver) snapshot(rc RenderConfig) (string, error) {
+	dim, center, err := renderCenter(rc)
+	if err != nil {
+		return "", fmt.Errorf("mcwdrv.snapshot: %s", err)
+	}
+	// Keep folder name of world, mc2pbrt may use it
+	dir := path.Join(drv.path.workdir, "snapshot", filepath.Base(rc.World))
+	if err := os.RemoveAll(path.Dir(dir)); err != nil {
+		return "", fmt.Errorf("mcwdrv.snapshot: %s", err)
+	}
+	err = takeSnapshot(rc.World, dir, dim, mc.BoxAround(center, rc.Radius))
+	if err != nil {
+		return "", fmt.Errorf("mcwdrv.snapshot: %s", err)
+	}
+	return dir, nil
+}
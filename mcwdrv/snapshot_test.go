@@ -0,0 +1,82 @@
+package mcwdrv
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/PbrtCraft/pbrtcraftdrv/mc"
+	"github.com/google/go-cmp/cmp"
+)
+
+func writeFiles(t *testing.T, dir string, files ...string) {
+	for _, fn := range files {
+		fn = path.Join(dir, fn)
+		if err := os.MkdirAll(path.Dir(fn), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fn, []byte(fn), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func listFiles(t *testing.T, dir string) []string {
+	ret := []string{}
+	err := filepath.Walk(dir, func(fn string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			rel, _ := filepath.Rel(dir, fn)
+			ret = append(ret, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func TestTakeSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	world := path.Join(dir, "world")
+	writeFiles(t, world,
+		"level.dat", "session.lock", "playerdata/a.dat", "playerdata/a.dat_old",
+		"region/r.-1.0.mca", "region/r.0.0.mca", "region/r.3.3.mca",
+		"region/c.-1.5.mcc", "region/c.40.5.mcc",
+		"DIM-1/region/r.0.0.mca", "stats/a.json",
+	)
+
+	// Blocks -8..8 overlap regions (-1, 0) and (0, 0)
+	snap := path.Join(dir, "snap")
+	box := mc.BoxAround([3]int{0, 64, 8}, 8)
+	if err := takeSnapshot(world, snap, mc.DimensionOverworld, box); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"level.dat", "playerdata/a.dat",
+		"region/c.-1.5.mcc", "region/r.-1.0.mca", "region/r.0.0.mca",
+	}
+	if diff := cmp.Diff(want, listFiles(t, snap)); diff != "" {
+		t.Errorf("overworld mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := takeSnapshot(world, snap, mc.DimensionNether, box); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"DIM-1/region/r.0.0.mca", "level.dat", "playerdata/a.dat"}
+	if diff := cmp.Diff(want, listFiles(t, snap)); diff != "" {
+		t.Errorf("nether mismatch (-want +got):\n%s", diff)
+	}
+}
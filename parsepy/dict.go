@@ -0,0 +1,181 @@
+package parsepy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DictEntry is a key-value pair in a python dict literal
+type DictEntry struct {
+	Key   string `json:"key"`   // Key, unquoted if it is a str literal
+	Value string `json:"value"` // Python source of value
+	Line  int    `json:"line"`
+}
+
+// Dict is a python dict literal assigned to a name, like `type_map = {"A": TestA}`
+type Dict struct {
+	Name    string       `json:"name"`
+	Line    int          `json:"line"`
+	Entries []*DictEntry `json:"entries"`
+}
+
+var dictAssignPattern = regexp.MustCompile(`^\s*([A-Za-z_]\w*)\s*=\s*\{`)
+
+// GetDicts return dict literals assigned to a name in a python script,
+// at any level
+func GetDicts(filename string) ([]*Dict, error) {
+	lines, err := readLines(filename)
+	if err != nil {
+		return nil, fmt.Errorf("parsepy.GetDicts: %s", err)
+	}
+
+	ret := []*Dict{}
+	for ptr := 0; ptr < len(lines); ptr++ {
+		match := dictAssignPattern.FindStringSubmatchIndex(lines[ptr])
+		if match == nil {
+			continue
+		}
+		dict := &Dict{
+			Name: lines[ptr][match[2]:match[3]],
+			Line: ptr + 1,
+		}
+
+		// Collect items until the closing brace,
+		// item starts from the line its first character is on
+		var sc pyScanner
+		item, itemLine := "", 0
+		text := lines[ptr][match[1]:]
+		lineNo := ptr + 1
+		for {
+			closed := false
+			for i := 0; i < len(text); i++ {
+				ch := text[i]
+				if sc.quote == 0 && ch == '#' {
+					break
+				}
+				top := sc.feed(ch)
+				if top && ch == '}' {
+					closed = true
+					break
+				}
+				if top && ch == ',' {
+					dict.addEntry(item, itemLine)
+					item, itemLine = "", 0
+					continue
+				}
+				if itemLine == 0 && ch != ' ' && ch != '\t' {
+					itemLine = lineNo
+				}
+				item += string(ch)
+			}
+			if closed {
+				break
+			}
+			ptr++
+			if ptr >= len(lines) {
+				return nil, fmt.Errorf("parsepy.GetDicts: %s:%d: unclosed dict %s",
+					filename, dict.Line, dict.Name)
+			}
+			text, lineNo = lines[ptr], ptr+1
+			item += " "
+		}
+		dict.addEntry(item, itemLine)
+		ret = append(ret, dict)
+	}
+	return ret, nil
+}
+
+// GetDict return the dict named name, nil if not found
+func GetDict(dicts []*Dict, name string) *Dict {
+	for _, dict := range dicts {
+		if dict.Name == name {
+			return dict
+		}
+	}
+	return nil
+}
+
+func (d *Dict) addEntry(item string, line int) {
+	item = strings.TrimSpace(item)
+	if item == "" {
+		return
+	}
+	// Split at the first top level colon
+	var sc pyScanner
+	for i := 0; i < len(item); i++ {
+		if sc.feed(item[i]) && item[i] == ':' {
+			key := strings.TrimSpace(item[:i])
+			if str, ok := parseStrLiteral(key); ok {
+				key = str
+			}
+			d.Entries = append(d.Entries, &DictEntry{
+				Key:   key,
+				Value: strings.TrimSpace(item[i+1:]),
+				Line:  line,
+			})
+			return
+		}
+	}
+}
+
+// Registry return classes exposed by registry dict, which maps public name
+// to class name, and registered names whose class is not found
+func Registry(classes []*Class, registry *Dict) ([]*Class, []string) {
+	byName := map[string]*Class{}
+	for _, class := range classes {
+		byName[class.Name] = class
+	}
+
+	exposed := []*Class{}
+	missing := []string{}
+	for _, entry := range registry.Entries {
+		class, ok := byName[entry.Value]
+		if !ok {
+			missing = append(missing, entry.Key)
+			continue
+		}
+		public := *class
+		if entry.Key != class.Name {
+			public.Name = entry.Key
+			public.ClassName = class.Name
+		}
+		exposed = append(exposed, &public)
+	}
+	return exposed, missing
+}
+
+// pyScanner tracks brackets and string literals of python source
+type pyScanner struct {
+	depth  int
+	quote  byte
+	escape bool
+}
+
+// feed return true if ch is at top level, outside of string and brackets
+func (sc *pyScanner) feed(ch byte) bool {
+	if sc.quote != 0 {
+		if sc.escape {
+			sc.escape = false
+		} else if ch == '\\' {
+			sc.escape = true
+		} else if ch == sc.quote {
+			sc.quote = 0
+		}
+		return false
+	}
+	switch ch {
+	case '"', '\'':
+		sc.quote = ch
+		return false
+	case '(', '[', '{':
+		sc.depth++
+		return false
+	case ')', ']', '}':
+		if sc.depth > 0 {
+			sc.depth--
+			return false
+		}
+	}
+	return sc.depth == 0
+}
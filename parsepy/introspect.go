@@ -0,0 +1,296 @@
+package parsepy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// RuntimeTimeout is how long GetClassesRuntime waits for python,
+// a script blocking on import is killed after it
+var RuntimeTimeout = 10 * time.Second
+
+// introspectScript imports the file given in argv[1] and dumps its classes
+// as JSON, in the same layout as Class
+const introspectScript = `
+import importlib.util
+import inspect
+import json
+import os
+import sys
+
+filename = os.path.abspath(sys.argv[1])
+dirname = os.path.dirname(filename)
+sys.path[0:0] = [dirname, os.path.dirname(dirname)]
+name = os.path.splitext(os.path.basename(filename))[0]
+spec = importlib.util.spec_from_file_location(name, filename)
+module = importlib.util.module_from_spec(spec)
+sys.modules[name] = module
+spec.loader.exec_module(module)
+
+
+def first_line(obj):
+    try:
+        return inspect.getsourcelines(obj)[0][0].strip()
+    except (OSError, TypeError, IndexError):
+        return ""
+
+
+def first_line_no(obj):
+    try:
+        return inspect.getsourcelines(obj)[1]
+    except (OSError, TypeError):
+        return 0
+
+
+def own_doc(obj):
+    # inspect.getdoc falls back to docs of base classes
+    doc = getattr(obj, "__doc__", None)
+    return inspect.cleandoc(doc) if doc else ""
+
+
+def annotation_name(annotation):
+    if annotation is inspect.Parameter.empty:
+        return None
+    if isinstance(annotation, type):
+        return annotation.__name__
+    return str(annotation)
+
+
+classes = []
+for cls_name, cls in inspect.getmembers(module, inspect.isclass):
+    if cls.__module__ != name:
+        continue
+    try:
+        sig = inspect.signature(cls)
+    except (TypeError, ValueError):
+        continue
+    params = []
+    for param in sig.parameters.values():
+        if param.kind in (param.VAR_POSITIONAL, param.VAR_KEYWORD):
+            continue
+        default = None
+        if param.default is not inspect.Parameter.empty:
+            default = repr(param.default)
+        params.append({
+            "name": param.name,
+            "annotation": annotation_name(param.annotation),
+            "default": default,
+        })
+    init = cls.__dict__.get("__init__")
+    classes.append({
+        "def": first_line(cls),
+        "name": cls_name,
+        "doc": own_doc(cls),
+        "line": first_line_no(cls),
+        "init_def": first_line(init) if init else "",
+        "init_line": first_line_no(init) if init else 0,
+        "init_doc": own_doc(init) if init else "",
+        "params": params,
+    })
+
+classes.sort(key=lambda c: c["line"])
+json.dump(classes, sys.stdout)
+`
+
+// GetClassesRuntime return class info by importing the python script with
+// interpreter python and inspecting it.
+// Unlike GetClasses, it sees decorators, dataclasses and computed defaults,
+// but the script and its imports must be runnable within RuntimeTimeout.
+func GetClassesRuntime(python, filename string) ([]*Class, error) {
+	absFilename, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, fmt.Errorf("parsepy.GetClassesRuntime: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), RuntimeTimeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, python, "-c", introspectScript, absFilename)
+	cmd.Dir = filepath.Dir(absFilename)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("parsepy.GetClassesRuntime: %s not imported in %s", filename, RuntimeTimeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsepy.GetClassesRuntime: %s: %s",
+			err, strings.TrimSpace(stderr.String()))
+	}
+
+	var dumped []struct {
+		Def      string `json:"def"`
+		Name     string `json:"name"`
+		Doc      string `json:"doc"`
+		Line     int    `json:"line"`
+		InitDef  string `json:"init_def"`
+		InitLine int    `json:"init_line"`
+		InitDoc  string `json:"init_doc"`
+		Params   []struct {
+			Name       string  `json:"name"`
+			Annotation *string `json:"annotation"`
+			Default    *string `json:"default"`
+		} `json:"params"`
+	}
+	err = json.Unmarshal(stdout.Bytes(), &dumped)
+	if err != nil {
+		return nil, fmt.Errorf("parsepy.GetClassesRuntime: %s", err)
+	}
+
+	ret := []*Class{}
+	for _, d := range dumped {
+		class := &Class{
+			Def:  d.Def,
+			Line: d.Line,
+			Name: d.Name,
+			Doc:  d.Doc,
+			InitFunc: Function{
+				Def:    d.InitDef,
+				Line:   d.InitLine,
+				Doc:    d.InitDoc,
+				Params: []*Param{},
+			},
+		}
+		for _, p := range d.Params {
+			// Same rules as paramsFromInitDef
+			param := &Param{Name: p.Name}
+			switch {
+			case p.Annotation != nil && p.Default != nil:
+				param.Type = getType(*p.Annotation)
+				param.DefaultValue = *p.Default
+				param.HasDefault = true
+			case p.Annotation != nil:
+				param.Type = getType(*p.Annotation)
+				param.DefaultValue = typeDefault(param.Type)
+			case p.Default != nil:
+				param.Type = getTypeByValue(*p.Default)
+				param.DefaultValue = *p.Default
+				param.HasDefault = true
+			default:
+				param.Type = ParamTypeInt
+				param.DefaultValue = "0"
+			}
+			class.InitFunc.Params = append(class.InitFunc.Params, param)
+		}
+		paramDocFrmoFunctionDoc(&class.InitFunc)
+		ret = append(ret, class)
+	}
+	return ret, nil
+}
+
+// Difference is a disagreement between two lists of classes
+type Difference struct {
+	Class   string `json:"class"`
+	Param   string `json:"param,omitempty"`
+	Field   string `json:"field"`
+	Static  string `json:"static"`
+	Runtime string `json:"runtime"`
+}
+
+// Compare return where classes from GetClasses and GetClassesRuntime disagree
+func Compare(static, runtime []*Class) []*Difference {
+	ret := []*Difference{}
+	runtimeByName := map[string]*Class{}
+	for _, class := range runtime {
+		runtimeByName[class.Name] = class
+	}
+	staticByName := map[string]*Class{}
+	for _, class := range static {
+		staticByName[class.Name] = class
+	}
+
+	for _, sc := range static {
+		rc, ok := runtimeByName[sc.Name]
+		if !ok {
+			ret = append(ret, &Difference{
+				Class: sc.Name, Field: "class", Static: sc.Def,
+			})
+			continue
+		}
+		if normalizeDoc(sc.Doc) != normalizeDoc(rc.Doc) {
+			ret = append(ret, &Difference{
+				Class: sc.Name, Field: "doc", Static: sc.Doc, Runtime: rc.Doc,
+			})
+		}
+		ret = append(ret, compareParams(sc.Name, sc.InitFunc.Params, rc.InitFunc.Params)...)
+	}
+	for _, rc := range runtime {
+		if _, ok := staticByName[rc.Name]; !ok {
+			ret = append(ret, &Difference{
+				Class: rc.Name, Field: "class", Runtime: rc.Def,
+			})
+		}
+	}
+	return ret
+}
+
+func compareParams(className string, static, runtime []*Param) []*Difference {
+	ret := []*Difference{}
+	runtimeByName := map[string]*Param{}
+	for _, param := range runtime {
+		runtimeByName[param.Name] = param
+	}
+	staticByName := map[string]*Param{}
+	for _, param := range static {
+		staticByName[param.Name] = param
+	}
+
+	for _, sp := range static {
+		rp, ok := runtimeByName[sp.Name]
+		if !ok {
+			ret = append(ret, &Difference{
+				Class: className, Param: sp.Name, Field: "param", Static: sp.Name,
+			})
+			continue
+		}
+		if sp.Type != rp.Type {
+			ret = append(ret, &Difference{
+				Class: className, Param: sp.Name, Field: "type",
+				Static: string(sp.Type), Runtime: string(rp.Type),
+			})
+		}
+		// Compare values, python repr may quote strings differently
+		sv, serr := sp.Value()
+		rv, rerr := rp.Value()
+		if serr != nil || rerr != nil || sv != rv {
+			if sp.DefaultValue != rp.DefaultValue {
+				ret = append(ret, &Difference{
+					Class: className, Param: sp.Name, Field: "default_value",
+					Static: sp.DefaultValue, Runtime: rp.DefaultValue,
+				})
+			}
+		}
+		if sp.Doc != rp.Doc {
+			ret = append(ret, &Difference{
+				Class: className, Param: sp.Name, Field: "doc",
+				Static: sp.Doc, Runtime: rp.Doc,
+			})
+		}
+	}
+	for _, rp := range runtime {
+		if _, ok := staticByName[rp.Name]; !ok {
+			ret = append(ret, &Difference{
+				Class: className, Param: rp.Name, Field: "param", Runtime: rp.Name,
+			})
+		}
+	}
+	return ret
+}
+
+// normalizeDoc ignore indentation and blank lines of docstring
+func normalizeDoc(doc string) string {
+	lines := []string{}
+	for _, line := range strings.Split(doc, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,116 @@
+package parsepy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Problem is a mistake in a python script found by Lint
+type Problem struct {
+	Filename string `json:"filename"`
+	Line     int    `json:"line"`
+	Message  string `json:"message"`
+}
+
+func (p *Problem) String() string {
+	return fmt.Sprintf("%s:%d: %s", p.Filename, p.Line, p.Message)
+}
+
+// Lint check classes in a python script for mistakes which would show up
+// in dashboard, registry is the name of factory dict, empty for not checking
+func Lint(filename, registry string) ([]*Problem, error) {
+	lines, err := readLines(filename)
+	if err != nil {
+		return nil, fmt.Errorf("parsepy.Lint: %s", err)
+	}
+	classes, err := GetClasses(filename)
+	if err != nil {
+		return nil, fmt.Errorf("parsepy.Lint: %s", err)
+	}
+
+	ret := []*Problem{}
+	report := func(line int, format string, a ...interface{}) {
+		ret = append(ret, &Problem{
+			Filename: filename,
+			Line:     line,
+			Message:  fmt.Sprintf(format, a...),
+		})
+	}
+
+	for _, class := range classes {
+		init := class.InitFunc
+		if init.Def == "" {
+			continue
+		}
+
+		args := map[string]bool{}
+		initArgs, ok := initArgsFromDef(init.Def)
+		if !ok {
+			report(init.Line, "%s: def __init__ is not on one line, params can not be read", class.Name)
+			continue
+		}
+		for _, arg := range initArgs {
+			args[arg.name] = true
+			if !arg.hasAnnotation {
+				report(init.Line, "%s: param %s has no type annotation", class.Name, arg.name)
+			} else if !isParamType(arg.annotation) {
+				report(init.Line, "%s: param %s has unsupported type %s, it is edited as str",
+					class.Name, arg.name, arg.annotation)
+			} else if arg.hasDefault {
+				param := Param{Type: getType(arg.annotation), DefaultValue: arg.defaultValue}
+				if _, err := ParseLiteral(arg.defaultValue); err != nil {
+					report(init.Line, "%s: default of param %s is not a literal: %s",
+						class.Name, arg.name, arg.defaultValue)
+				} else if _, err := param.Value(); err != nil {
+					report(init.Line, "%s: default of param %s does not match %s: %s",
+						class.Name, arg.name, arg.annotation, arg.defaultValue)
+				}
+			}
+		}
+
+		// Docstring of __init__ starts after def line,
+		// and ends before the first line less indented than its body
+		defLevel, _ := levelString(lines[init.Line-1])
+		for ptr := init.Line; ptr < len(lines); ptr++ {
+			level, line := levelString(lines[ptr])
+			if line == "" {
+				continue
+			}
+			if level <= defLevel {
+				break
+			}
+			name, _, ok := docParam(line)
+			if ok && !args[name] {
+				report(ptr+1, "%s: documented param %s does not exist", class.Name, name)
+			}
+		}
+	}
+
+	if registry != "" {
+		dicts, err := GetDicts(filename)
+		if err != nil {
+			return nil, fmt.Errorf("parsepy.Lint: %s", err)
+		}
+		if dict := GetDict(dicts, registry); dict != nil {
+			_, missing := Registry(classes, dict)
+			for _, entry := range dict.Entries {
+				for _, name := range missing {
+					if entry.Key == name {
+						report(entry.Line, "%s: registered class %s not found", registry, entry.Value)
+					}
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Line < ret[j].Line
+	})
+	return ret, nil
+}
+
+// isParamType check whether a python annotation is supported by dashboard
+func isParamType(annotation string) bool {
+	return strings.TrimSpace(annotation) == string(getType(annotation))
+}
@@ -20,37 +20,37 @@ type Param struct {
 	Doc          string    `json:"doc"`
 	Type         ParamType `json:"type"`
 	DefaultValue string    `json:"default_value"`
+	// HasDefault is false if __init__ has no default for the param,
+	// DefaultValue is then the default of its type
+	HasDefault bool `json:"has_default"`
 }
 
 type Function struct {
 	Def    string   `json:"def"`
+	Line   int      `json:"line"`
 	Doc    string   `json:"doc"`
 	Params []*Param `json:"params"`
 }
 
 type Class struct {
 	Def  string `json:"def"`
+	Line int    `json:"line"`
 	Name string `json:"name"`
 	Doc  string `json:"doc"`
 
+	// ClassName is set when Name is a registry name different from python class name
+	ClassName string `json:"class_name,omitempty"`
+
 	InitFunc Function `json:"init_func"`
 }
 
 // GetClasses return class info in a python script
 // python script should be pep8
 func GetClasses(filename string) ([]*Class, error) {
-	file, err := os.Open(filename)
+	lines, err := readLines(filename)
 	if err != nil {
 		return nil, fmt.Errorf("parsepy.GetClasses: %s", err)
 	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	lines := []string{}
-
-	for scanner.Scan() {
-		lines = append(lines, strings.TrimRight(scanner.Text(), " 	"))
-	}
 
 	type blockID int
 	const (
@@ -86,9 +86,12 @@ func GetClasses(filename string) ([]*Class, error) {
 		if level == 0 {
 			if isBlockStart(line) {
 				if strings.HasPrefix(line, "class ") {
+					// Base classes are not part of the name
+					name := strings.SplitN(line[6:len(line)-1], "(", 2)[0]
 					class := Class{
-						Name: line[6 : len(line)-1],
+						Name: strings.TrimSpace(name),
 						Def:  line,
+						Line: ptr + 1,
 					}
 					ret = append(ret, &class)
 					rootLevelBlock = classBlock
@@ -104,6 +107,7 @@ func GetClasses(filename string) ([]*Class, error) {
 				if rootLevelBlock == classBlock {
 					backPtr := len(ret) - 1
 					ret[backPtr].InitFunc.Def = line
+					ret[backPtr].InitFunc.Line = ptr + 1
 					ret[backPtr].InitFunc.Params, _ = paramsFromInitDef(line)
 					nextLevelBlock = initFuncBlock
 				}
@@ -125,6 +129,9 @@ func GetClasses(filename string) ([]*Class, error) {
 			docString := line[3:] + "\n"
 			for {
 				ptr++
+				if ptr >= len(lines) {
+					return nil, fmt.Errorf("parsepy.GetClasses: %s: unterminated docstring", filename)
+				}
 				_, line := levelString(lines[ptr])
 				if strings.HasPrefix(line, `"""`) {
 					break
@@ -140,6 +147,25 @@ func GetClasses(filename string) ([]*Class, error) {
 	return ret, nil
 }
 
+func readLines(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("parsepy.readLines: %s", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lines := []string{}
+
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimRight(scanner.Text(), " 	"))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("parsepy.readLines: %s", err)
+	}
+	return lines, nil
+}
+
 func levelString(line string) (int, string) {
 	level := 0
 	for strings.HasPrefix(line, "    ") {
@@ -159,41 +185,106 @@ func isBlockStart(line string) bool {
 	return false
 }
 
-func paramsFromInitDef(def string) ([]*Param, error) {
+// initArg is an argument of __init__ as it is written
+type initArg struct {
+	name          string
+	annotation    string
+	defaultValue  string
+	hasAnnotation bool
+	hasDefault    bool
+}
+
+// initArgsFromDef split arguments of def line except self, *args and **kwargs,
+// false if the def line does not hold all arguments, like one continued on next lines
+func initArgsFromDef(def string) ([]*initArg, bool) {
 	// def __init__(self, ...):
-	argStrs := strings.Split(def[13:len(def)-2], ",")
-	params := []*Param{}
+	const prefix = "def __init__("
+	def = strings.TrimSpace(def)
+	end := strings.LastIndex(def, ")")
+	if !strings.HasPrefix(def, prefix) || !strings.HasSuffix(def, ":") || end < len(prefix) {
+		return nil, false
+	}
+	argsStr := def[len(prefix):end]
+
+	argStrs := []string{}
+	var sc pyScanner
+	start := 0
+	for i := 0; i < len(argsStr); i++ {
+		if sc.feed(argsStr[i]) && argsStr[i] == ',' {
+			argStrs = append(argStrs, argsStr[start:i])
+			start = i + 1
+		}
+	}
+	argStrs = append(argStrs, argsStr[start:])
+
+	args := []*initArg{}
 	for _, argStr := range argStrs[1:] {
+		argStr = strings.TrimSpace(argStr)
+		if argStr == "" || strings.HasPrefix(argStr, "*") {
+			continue
+		}
+		arg := &initArg{}
+		var sc pyScanner
+		colon, equal := -1, -1
+		for i := 0; i < len(argStr) && equal == -1; i++ {
+			if !sc.feed(argStr[i]) {
+				continue
+			}
+			if argStr[i] == ':' && colon == -1 {
+				colon = i
+			} else if argStr[i] == '=' {
+				equal = i
+			}
+		}
+		nameEnd := len(argStr)
+		if equal != -1 {
+			arg.hasDefault = true
+			arg.defaultValue = strings.TrimSpace(argStr[equal+1:])
+			nameEnd = equal
+		}
+		if colon != -1 {
+			arg.hasAnnotation = true
+			arg.annotation = strings.TrimSpace(argStr[colon+1 : nameEnd])
+			nameEnd = colon
+		}
+		arg.name = strings.TrimSpace(argStr[:nameEnd])
+		args = append(args, arg)
+	}
+	return args, true
+}
+
+func paramsFromInitDef(def string) ([]*Param, error) {
+	params := []*Param{}
+	args, ok := initArgsFromDef(def)
+	if !ok {
+		return params, fmt.Errorf("parsepy.paramsFromInitDef: def is not on one line: %s", def)
+	}
+	for _, arg := range args {
 		var param Param
-		hasType := strings.Count(argStr, ":") > 0
-		hasDefaultValue := strings.Count(argStr, "=") > 0
-		if hasType && hasDefaultValue {
-			part1 := strings.Split(argStr, ":")
-			part2 := strings.Split(part1[1], "=")
+		if arg.hasAnnotation && arg.hasDefault {
 			param = Param{
-				Name:         strings.TrimSpace(part1[0]),
-				Type:         getType(strings.TrimSpace(part2[0])),
-				DefaultValue: strings.TrimSpace(part2[1]),
+				Name:         arg.name,
+				Type:         getType(arg.annotation),
+				DefaultValue: arg.defaultValue,
+				HasDefault:   true,
 			}
-		} else if hasType {
-			part1 := strings.Split(argStr, ":")
-			tp := getType(strings.TrimSpace(part1[1]))
+		} else if arg.hasAnnotation {
+			tp := getType(arg.annotation)
 			param = Param{
-				Name:         strings.TrimSpace(part1[0]),
+				Name:         arg.name,
 				Type:         tp,
 				DefaultValue: typeDefault(tp),
 			}
-		} else if hasDefaultValue {
-			part1 := strings.Split(argStr, "=")
-			defaultValue := strings.TrimSpace(part1[1])
+		} else if arg.hasDefault {
 			param = Param{
-				Name:         strings.TrimSpace(part1[0]),
-				Type:         getTypeByValue(defaultValue),
-				DefaultValue: defaultValue,
+				Name:         arg.name,
+				Type:         getTypeByValue(arg.defaultValue),
+				DefaultValue: arg.defaultValue,
+				HasDefault:   true,
 			}
 		} else {
 			param = Param{
-				Name:         strings.TrimSpace(argStr),
+				Name:         arg.name,
 				Type:         ParamTypeInt,
 				DefaultValue: "0",
 			}
@@ -206,25 +297,33 @@ func paramsFromInitDef(def string) ([]*Param, error) {
 func paramDocFrmoFunctionDoc(f *Function) {
 	lines := strings.Split(f.Doc, "\n")
 	for _, line := range lines {
-		parts := strings.Split(line, ":")
-		// Should be :param *: *
-		if len(parts) != 3 {
+		name, doc, ok := docParam(line)
+		if !ok {
 			continue
 		}
-		if !(parts[0] == "" && strings.HasPrefix(parts[1], "param ")) {
-			continue
-		}
-		name := parts[1][6:]
 
 		for _, param := range f.Params {
 			if param.Name == name {
-				param.Doc = strings.TrimSpace(parts[2])
+				param.Doc = doc
 				break
 			}
 		}
 	}
 }
 
+// docParam parse a ":param name: doc" line of docstring
+func docParam(line string) (string, string, bool) {
+	parts := strings.Split(strings.TrimSpace(line), ":")
+	// Should be :param *: *
+	if len(parts) != 3 {
+		return "", "", false
+	}
+	if !(parts[0] == "" && strings.HasPrefix(parts[1], "param ")) {
+		return "", "", false
+	}
+	return parts[1][6:], strings.TrimSpace(parts[2]), true
+}
+
 func getType(t string) ParamType {
 	switch t {
 	case "str":
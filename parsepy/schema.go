@@ -0,0 +1,251 @@
+package parsepy
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SchemaDraft is the JSON Schema dialect used by Schema
+const SchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// Schema is a JSON Schema document
+type Schema struct {
+	Schema      string             `json:"$schema,omitempty"`
+	Title       string             `json:"title,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Type        string             `json:"type"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Default     interface{}        `json:"default,omitempty"`
+
+	AdditionalProperties *bool `json:"additionalProperties,omitempty"`
+}
+
+// JSONSchema return the schema of arguments of class's __init__
+func (c *Class) JSONSchema() *Schema {
+	noAdditional := false
+	ret := &Schema{
+		Schema:      SchemaDraft,
+		Title:       c.Name,
+		Description: strings.TrimSpace(c.Doc),
+		Type:        "object",
+		Properties:  map[string]*Schema{},
+		Required:    []string{},
+
+		AdditionalProperties: &noAdditional,
+	}
+	for _, param := range c.InitFunc.Params {
+		prop := &Schema{
+			Description: param.Doc,
+			Type:        schemaType(param.Type),
+		}
+		// Default which cannot be converted is left empty,
+		// and the param is required like one without default
+		value, err := param.Value()
+		if err == nil {
+			prop.Default = value
+		}
+		ret.Properties[param.Name] = prop
+		if !param.HasDefault || err != nil {
+			ret.Required = append(ret.Required, param.Name)
+		}
+	}
+	return ret
+}
+
+// Value return default value of param converted to a JSON value
+func (p *Param) Value() (interface{}, error) {
+	if p.Type == ParamTypeStr && p.DefaultValue == "" {
+		// typeDefault of str
+		return "", nil
+	}
+	value, err := ParseLiteral(p.DefaultValue)
+	if err != nil {
+		return nil, fmt.Errorf("parsepy.Param.Value: %s", err)
+	}
+
+	switch p.Type {
+	case ParamTypeFloat:
+		switch v := value.(type) {
+		case int64:
+			return float64(v), nil
+		case float64:
+			return v, nil
+		}
+	case ParamTypeInt:
+		switch v := value.(type) {
+		case int64:
+			return v, nil
+		case float64:
+			if v == float64(int64(v)) {
+				return int64(v), nil
+			}
+		}
+	case ParamTypeStr:
+		if v, ok := value.(string); ok {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("parsepy.Param.Value: %q is not %s", p.DefaultValue, p.Type)
+}
+
+// ParseLiteral convert a python literal into a Go value,
+// result is int64, float64, string, bool or nil
+func ParseLiteral(s string) (interface{}, error) {
+	s = strings.TrimSpace(s)
+	switch s {
+	case "":
+		return nil, fmt.Errorf("parsepy.ParseLiteral: empty literal")
+	case "None":
+		return nil, nil
+	case "True":
+		return true, nil
+	case "False":
+		return false, nil
+	}
+
+	if str, ok := parseStrLiteral(s); ok {
+		return str, nil
+	}
+
+	num := strings.Replace(s, "_", "", -1)
+	if i, ok := parseIntLiteral(num); ok {
+		return i, nil
+	}
+	// Python floats have a point or exponent, Go also takes hex floats,
+	// inf and nan, which are not python literals
+	if strings.ContainsAny(num, ".eE") && !strings.ContainsAny(num, "xXpP") {
+		f, err := strconv.ParseFloat(num, 64)
+		if err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("parsepy.ParseLiteral: unsupported literal %s", s)
+}
+
+// parseIntLiteral parse decimal, or 0x, 0o and 0b prefixed int as python 3,
+// where decimal with leading zeros like 010 is an error
+func parseIntLiteral(s string) (int64, bool) {
+	sign := ""
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		sign, s = s[:1], s[1:]
+	}
+	base := 10
+	if len(s) > 2 && s[0] == '0' {
+		switch s[1] {
+		case 'x', 'X':
+			base = 16
+		case 'o', 'O':
+			base = 8
+		case 'b', 'B':
+			base = 2
+		}
+		if base != 10 {
+			s = s[2:]
+		}
+	}
+	if base == 10 && len(s) > 1 && s[0] == '0' && strings.Trim(s, "0") != "" {
+		return 0, false
+	}
+	if s == "" || s[0] == '+' || s[0] == '-' {
+		return 0, false
+	}
+	i, err := strconv.ParseInt(sign+s, base, 64)
+	if err != nil {
+		return 0, false
+	}
+	return i, true
+}
+
+// pyEscapes is single character escapes of python strings,
+// backslash and newline continue the line
+var pyEscapes = map[byte]string{
+	'\n': "", '\\': "\\", '\'': "'", '"': "\"", 'a': "\a", 'b': "\b",
+	'f': "\f", 'n': "\n", 'r': "\r", 't': "\t", 'v': "\v",
+}
+
+// parseStrLiteral unquote a python string literal in single or double quotes,
+// with an optional r prefix. Unknown escapes are kept like python does.
+func parseStrLiteral(s string) (string, bool) {
+	raw := false
+	if len(s) > 0 && (s[0] == 'r' || s[0] == 'R') {
+		raw, s = true, s[1:]
+	}
+	if len(s) < 2 {
+		return "", false
+	}
+	quote := s[0]
+	if (quote != '"' && quote != '\'') || s[len(s)-1] != quote {
+		return "", false
+	}
+	body := s[1 : len(s)-1]
+
+	var b strings.Builder
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		if c == quote || c == '\n' {
+			return "", false
+		}
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		if i == len(body) {
+			// The backslash escapes the closing quote
+			return "", false
+		}
+		c = body[i]
+		if raw {
+			b.WriteByte('\\')
+			b.WriteByte(c)
+			continue
+		}
+		if esc, ok := pyEscapes[c]; ok {
+			b.WriteString(esc)
+			continue
+		}
+		switch {
+		case c >= '0' && c <= '7':
+			n := 1
+			for n < 3 && i+n < len(body) && body[i+n] >= '0' && body[i+n] <= '7' {
+				n++
+			}
+			v, _ := strconv.ParseUint(body[i:i+n], 8, 32)
+			b.WriteRune(rune(v))
+			i += n - 1
+		case c == 'x' || c == 'u' || c == 'U':
+			n := map[byte]int{'x': 2, 'u': 4, 'U': 8}[c]
+			if i+n >= len(body) {
+				return "", false
+			}
+			v, err := strconv.ParseUint(body[i+1:i+1+n], 16, 32)
+			if err != nil || v > unicode.MaxRune {
+				return "", false
+			}
+			b.WriteRune(rune(v))
+			i += n
+		case c == 'N':
+			// Named unicode characters are not supported
+			return "", false
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), true
+}
+
+func schemaType(t ParamType) string {
+	switch t {
+	case ParamTypeInt:
+		return "integer"
+	case ParamTypeFloat:
+		return "number"
+	default:
+		return "string"
+	}
+}
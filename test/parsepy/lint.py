@@ -0,0 +1,34 @@
+def create(name, args):
+    type_map = {"good": Good, "bad": Bad, "gone": Gone}
+    return type_map[name](**args)
+
+
+class Good:
+    """Good one"""
+
+    def __init__(self, a: int = 1, b: float = 2, c: str = "c"):
+        """Initial
+        :param a: apple
+        :param b: bus
+        """
+
+
+class Bad:
+    """Bad one"""
+
+    def __init__(self, a, b: int = 1.5, c: str = 3, d: bool = True, e: float = SCALE):
+        """Initial
+        :param a: apple
+        :param z: zoo
+        """
+
+
+class Wrapped:
+    """Wrapped def"""
+
+    def __init__(
+        self, a: int = 1
+    ):
+        """Initial
+        :param a: apple
+        """
@@ -0,0 +1,34 @@
+package parsepy_test
+
+import (
+	"testing"
+
+	"github.com/PbrtCraft/pbrtcraftdrv/parsepy"
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestLint test problems found in lint.py
+func TestLint(t *testing.T) {
+	problems, err := parsepy.Lint("lint.py", "type_map")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, problem := range problems {
+		got = append(got, problem.String())
+	}
+	want := []string{
+		"lint.py:2: type_map: registered class Gone not found",
+		"lint.py:19: Bad: param a has no type annotation",
+		"lint.py:19: Bad: default of param b does not match int: 1.5",
+		"lint.py:19: Bad: default of param c does not match str: 3",
+		"lint.py:19: Bad: param d has unsupported type bool, it is edited as str",
+		"lint.py:19: Bad: default of param e is not a literal: SCALE",
+		"lint.py:22: Bad: documented param z does not exist",
+		"lint.py:29: Wrapped: def __init__ is not on one line, params can not be read",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
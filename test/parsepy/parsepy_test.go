@@ -17,25 +17,29 @@ func TestParse(t *testing.T) {
 	want := []*parsepy.Class{
 		{
 			Def:  "class TestA:",
+			Line: 14,
 			Name: "TestA",
 			Doc:  "A is a\nJzzzz\n",
 			InitFunc: parsepy.Function{
-				Def: "def __init__(self, a: int, b: str, c: float = 1):",
-				Doc: "Initial\n:param a: apple\n:param b: bus\n",
+				Def:  "def __init__(self, a: int, b: str, c: float = 1):",
+				Line: 19,
+				Doc:  "Initial\n:param a: apple\n:param b: bus\n",
 				Params: []*parsepy.Param{
 					{Name: "a", Doc: "apple", Type: "int", DefaultValue: "0"},
 					{Name: "b", Doc: "bus", Type: "str"},
-					{Name: "c", Type: "float", DefaultValue: "1"},
+					{Name: "c", Type: "float", DefaultValue: "1", HasDefault: true},
 				},
 			},
 		},
 		{
 			Def:  "class TestB:",
+			Line: 27,
 			Name: "TestB",
 			Doc:  "B is b",
 			InitFunc: parsepy.Function{
-				Def: "def __init__(self, a, c: float):",
-				Doc: "Initial\n- a apple\n- b bus\n",
+				Def:  "def __init__(self, a, c: float):",
+				Line: 30,
+				Doc:  "Initial\n- a apple\n- b bus\n",
 				Params: []*parsepy.Param{
 					{Name: "a", Type: "int", DefaultValue: "0"},
 					{Name: "c", Type: "float", DefaultValue: "0.0"},
@@ -47,3 +51,42 @@ func TestParse(t *testing.T) {
 		t.Errorf("mismatch (-want +got):\n%s", diff)
 	}
 }
+
+// TestRegistry test exposing classes by type_map
+func TestRegistry(t *testing.T) {
+	classes, err := parsepy.GetClasses("test.py")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dicts, err := parsepy.GetDicts("test.py")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	typeMap := parsepy.GetDict(dicts, "type_map")
+	wantMap := &parsepy.Dict{
+		Name: "type_map",
+		Line: 3,
+		Entries: []*parsepy.DictEntry{
+			{Key: "A", Value: "TestA", Line: 4},
+			{Key: "B", Value: "TestB", Line: 5},
+			{Key: "C", Value: "TestC", Line: 6},
+		},
+	}
+	if diff := cmp.Diff(wantMap, typeMap); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+
+	exposed, missing := parsepy.Registry(classes, typeMap)
+	gotNames := [][2]string{}
+	for _, class := range exposed {
+		gotNames = append(gotNames, [2]string{class.Name, class.ClassName})
+	}
+	wantNames := [][2]string{{"A", "TestA"}, {"B", "TestB"}}
+	if diff := cmp.Diff(wantNames, gotNames); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"C"}, missing); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
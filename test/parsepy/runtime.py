@@ -0,0 +1,24 @@
+from dataclasses import dataclass
+
+SCALE = 2.0
+
+
+@dataclass
+class Sun:
+    """Sun light"""
+    power: float = SCALE * 3
+
+
+class Rain:
+    """Rain"""
+
+    def __init__(self, strength: float = SCALE, kind="drizzle"):
+        """Initial
+        :param strength: how heavy
+        """
+        pass
+
+
+class Hail(Rain):
+    def __init__(self, size: float = 1.0):
+        pass
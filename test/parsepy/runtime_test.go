@@ -0,0 +1,66 @@
+package parsepy_test
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/PbrtCraft/pbrtcraftdrv/parsepy"
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestCompareRuntime test static parsing agree with runtime on test.py,
+// and disagree on what static parsing cannot see. Hail has no docstrings,
+// which are not taken from Rain.
+func TestCompareRuntime(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not found")
+	}
+
+	static, err := parsepy.GetClasses("test.py")
+	if err != nil {
+		t.Fatal(err)
+	}
+	runtime, err := parsepy.GetClassesRuntime(python, "test.py")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := parsepy.Compare(static, runtime); len(diff) != 0 {
+		t.Errorf("test.py should agree, got %v", diff)
+	}
+
+	static, err = parsepy.GetClasses("runtime.py")
+	if err != nil {
+		t.Fatal(err)
+	}
+	runtime, err = parsepy.GetClassesRuntime(python, "runtime.py")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*parsepy.Difference{
+		{Class: "Sun", Param: "power", Field: "param", Runtime: "power"},
+		{Class: "Rain", Param: "strength", Field: "default_value", Static: "SCALE", Runtime: "2.0"},
+	}
+	if diff := cmp.Diff(want, parsepy.Compare(static, runtime)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestRuntimeTimeout test a script blocking on import is killed
+func TestRuntimeTimeout(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not found")
+	}
+
+	defer func(timeout time.Duration) { parsepy.RuntimeTimeout = timeout }(parsepy.RuntimeTimeout)
+	parsepy.RuntimeTimeout = 500 * time.Millisecond
+	start := time.Now()
+	if _, err := parsepy.GetClassesRuntime(python, "blocking.py"); err == nil {
+		t.Error("blocking.py should time out")
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Errorf("GetClassesRuntime took %s", d)
+	}
+}
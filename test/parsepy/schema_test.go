@@ -0,0 +1,82 @@
+package parsepy_test
+
+import (
+	"testing"
+
+	"github.com/PbrtCraft/pbrtcraftdrv/parsepy"
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestParseLiteral test converting python literal
+func TestParseLiteral(t *testing.T) {
+	cases := []struct {
+		literal string
+		want    interface{}
+	}{
+		{"1", int64(1)},
+		{"-2", int64(-2)},
+		{"1.5", 1.5},
+		{"1e3", 1000.0},
+		{`"abc"`, "abc"},
+		{`'a"b'`, `a"b`},
+		{`'it\'s'`, "it's"},
+		{`"it\'s"`, "it's"},
+		{`'say "hi"'`, `say "hi"`},
+		{`"say \"hi\""`, `say "hi"`},
+		{`'a\tb\\c'`, "a\tb\\c"},
+		{`'\x41\101\u00e9\0'`, "AAé\x00"},
+		{`'\d'`, `\d`},
+		{`r'\n\''`, `\n\'`},
+		{"True", true},
+		{"None", nil},
+		{"0x1F", int64(31)},
+		{"0o17", int64(15)},
+		{"-0b101", int64(-5)},
+		{"00", int64(0)},
+		{"1_000", int64(1000)},
+		{"010.5", 10.5},
+	}
+	for _, c := range cases {
+		got, err := parsepy.ParseLiteral(c.literal)
+		if err != nil {
+			t.Errorf("%s: %s", c.literal, err)
+			continue
+		}
+		if diff := cmp.Diff(c.want, got); diff != "" {
+			t.Errorf("%s mismatch (-want +got):\n%s", c.literal, diff)
+		}
+	}
+
+	for _, literal := range []string{"max(1, 2)", `'a'b'`, `"a\"`, `'\x4'`, `'\N{DASH}'`, "010", "inf", "-infinity", "nan", "1e400", "0x1p-2"} {
+		if _, err := parsepy.ParseLiteral(literal); err == nil {
+			t.Errorf("%s should not be a literal", literal)
+		}
+	}
+}
+
+// TestJSONSchema test schema of parsed classes
+func TestJSONSchema(t *testing.T) {
+	classes, err := parsepy.GetClasses("test.py")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	noAdditional := false
+	want := &parsepy.Schema{
+		Schema:      parsepy.SchemaDraft,
+		Title:       "TestA",
+		Description: "A is a\nJzzzz",
+		Type:        "object",
+		Properties: map[string]*parsepy.Schema{
+			"a": {Description: "apple", Type: "integer", Default: int64(0)},
+			"b": {Description: "bus", Type: "string", Default: ""},
+			"c": {Type: "number", Default: 1.0},
+		},
+		Required: []string{"a", "b"},
+
+		AdditionalProperties: &noAdditional,
+	}
+	if diff := cmp.Diff(want, classes[0].JSONSchema()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -3,6 +3,7 @@ def create(name, args):
     type_map = {
         "A": TestA,
         "B": TestB,
+        "C": TestC,  # Not implemented yet
     }
     if name in type_map:
         return type_map[name](**args)